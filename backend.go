@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gopsql/db"
@@ -19,11 +20,17 @@ type (
 		Name      string
 		Validator *validator.Validate
 
-		jwtSession jwtSession
-		models     []*psql.Model
-		logger     logger.Logger
-		migrator   *migrator.Migrator
-		dbConn     db.DB
+		jwtSession        jwtSession
+		models            []*psql.Model
+		logger            logger.Logger
+		migrator          *migrator.Migrator
+		dbConn            db.DB
+		retentionPeriod   time.Duration
+		keyCase           KeyCase
+		idempotencyPeriod time.Duration
+		idempotencyLock   time.Duration
+		tenantResolutions []TenantResolution
+		crossTenantAdmins bool
 	}
 
 	CanSkipMigration interface {
@@ -54,9 +61,13 @@ var Default = newDefaultBackend()
 // Create new backend instance.
 func NewBackend() *Backend {
 	return &Backend{
-		Validator: validator.New(),
-		logger:    logger.NoopLogger,
-		migrator:  migrator.NewMigrator(),
+		Validator:         validator.New(),
+		logger:            logger.NoopLogger,
+		migrator:          migrator.NewMigrator(),
+		retentionPeriod:   DefaultRetentionPeriod,
+		idempotencyPeriod: DefaultIdempotencyPeriod,
+		idempotencyLock:   DefaultIdempotencyLockTimeout,
+		tenantResolutions: DefaultTenantResolutions,
 	}
 }
 
@@ -66,7 +77,7 @@ func newDefaultBackend() *Backend {
 		if i, ok := fl.Top().Interface().(interface{ IsUnique(*Backend, string) bool }); ok {
 			return i.IsUnique(b, fl.StructFieldName())
 		}
-		return false
+		return b.IsUnique(fl.Top().Interface(), fl.StructFieldName())
 	})
 	return b
 }
@@ -86,6 +97,19 @@ func (backend *Backend) AddModelAdminSession() {
 	backend.NewModel(AdminSession{}, backend.dbConn, backend.logger)
 }
 
+// AddModelIdempotencyKey adds IdempotencyKey model to enable the
+// Idempotency-Key header, see FiberIdempotency().
+func (backend *Backend) AddModelIdempotencyKey() {
+	backend.NewModel(IdempotencyKey{}, backend.dbConn, backend.logger)
+}
+
+// AddModelTenant adds Tenant and TenantMembership models to partition
+// admins and records by tenant, see FiberTenantId().
+func (backend *Backend) AddModelTenant() {
+	backend.NewModel(Tenant{}, backend.dbConn, backend.logger)
+	backend.NewModel(TenantMembership{}, backend.dbConn, backend.logger)
+}
+
 // AddModels adds one or multiple psql.Model instances to backend.
 func (backend *Backend) AddModels(models ...*psql.Model) {
 	backend.models = append(backend.models, models...)
@@ -117,6 +141,47 @@ func (backend *Backend) SetMigrations(migrations interface{}) {
 	backend.migrator.SetMigrations(migrations)
 }
 
+// SetRetentionPeriod sets how long soft deleted records are kept before
+// they can be purged. See Purge().
+func (backend *Backend) SetRetentionPeriod(retentionPeriod time.Duration) {
+	backend.retentionPeriod = retentionPeriod
+}
+
+// SetIdempotencyPeriod sets how long responses of the requests with
+// Idempotency-Key header are replayed. See FiberIdempotency().
+func (backend *Backend) SetIdempotencyPeriod(idempotencyPeriod time.Duration) {
+	backend.idempotencyPeriod = idempotencyPeriod
+}
+
+// SetIdempotencyLockTimeout sets how long a request with Idempotency-Key
+// header can be in progress. Keys of the requests in progress for longer,
+// whose process may have crashed, are released. See FiberIdempotency().
+func (backend *Backend) SetIdempotencyLockTimeout(lockTimeout time.Duration) {
+	backend.idempotencyLock = lockTimeout
+}
+
+// SetTenantResolutions sets the ways to resolve the tenant of requests, in
+// order of precedence. Defaults to DefaultTenantResolutions.
+func (backend *Backend) SetTenantResolutions(resolutions ...TenantResolution) {
+	backend.tenantResolutions = resolutions
+}
+
+// SetCrossTenantAdmins sets whether admins who are not member of any tenant
+// can access every tenant, like superadmins. Disabled by default, so such
+// admins are denied by the tenants, see IsTenantMember().
+func (backend *Backend) SetCrossTenantAdmins(enabled bool) {
+	backend.crossTenantAdmins = enabled
+}
+
+// SetKeyCase sets the naming strategy of the keys of the JSON responses,
+// the error payloads of HandleError() and the names of their input errors,
+// converting keys of the JSON request bodies and the query params (sort,
+// fields, include and filters) back, so models don't need json tags.
+// Defaults to KeyCaseGo.
+func (backend *Backend) SetKeyCase(keyCase KeyCase) {
+	backend.keyCase = keyCase
+}
+
 func (backend *Backend) SetJWTSession(jwtSession jwtSession) {
 	backend.jwtSession = jwtSession
 }
@@ -165,6 +230,7 @@ const (
 	NoCreateMigration
 	NoMigrate
 	NoRollback
+	NoPurge
 )
 
 var allFlagUsages = flagUsages{
@@ -173,6 +239,7 @@ var allFlagUsages = flagUsages{
 	{"CREATE_MIGRATION=1", "generate new migration file"},
 	{"MIGRATE=1", "run new migrations"},
 	{"ROLLBACK=1", "rollback last migration"},
+	{"PURGE=1", "permanently delete records deleted before retention period"},
 }
 
 func (options flagUsageOptions) has(option flagUsageOption) bool {
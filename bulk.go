@@ -0,0 +1,134 @@
+package backend
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gopsql/psql"
+)
+
+// Bulk modes.
+const (
+	// All items are processed in a single transaction, nothing is changed
+	// if any of the items fails.
+	BulkTransaction = "transaction"
+
+	// Each item is processed in its own transaction, failed items do not
+	// affect others.
+	BulkBestEffort = "best-effort"
+)
+
+type (
+	// BulkRequest is the request body of bulk actions. Ids is used by
+	// actions like destroy and restore, Items is used by actions like
+	// update, each item should contain the Id.
+	BulkRequest struct {
+		Ids   []int
+		Items []json.RawMessage
+		Mode  string
+	}
+
+	// BulkResult contains IDs of succeeded items and errors of failed
+	// items.
+	BulkResult struct {
+		Ids    []int
+		Errors InputErrorsWithIndex
+	}
+)
+
+// Bulk calls action for n items, action should return ID of the processed
+// item. Input errors (InputErrors, validation errors and no rows error)
+// returned by the action are reported with the index of the item, as are
+// unique index violations (as "unique" errors of the unique field, like
+// FiberImport() does), other errors abort the whole bulk action.
+//
+// In transaction mode (the default), all items are processed in a single
+// transaction, which is rolled back if any of the items fails, in which case
+// InputErrorsWithIndex of all failed items is returned. Items after a unique
+// index violation are not processed, as the transaction is aborted. In best-effort mode,
+// each item is processed in its own transaction, and errors of failed items
+// are in the Errors of the result.
+func (backend Backend) Bulk(m *psql.Model, mode string, n int, action func(tx *psql.Tx, i int) (int, error)) (result BulkResult, err error) {
+	switch mode {
+	case "", BulkTransaction:
+		err = backend.Transaction(m, func(tx *psql.Tx) error {
+			for i := 0; i < n; i++ {
+				id, err := action(tx, i)
+				if err == nil {
+					result.Ids = append(result.Ids, id)
+					continue
+				}
+				if isUniqueViolation(err) {
+					result.Errors = append(result.Errors, uniqueViolationWithIndex(m, err, i)...)
+					return result.Errors
+				}
+				ierrs, ok := backend.inputErrorsWithIndex(err, i)
+				if !ok {
+					return err
+				}
+				result.Errors = append(result.Errors, ierrs...)
+			}
+			if len(result.Errors) > 0 {
+				return result.Errors
+			}
+			return nil
+		})
+		if err != nil {
+			result = BulkResult{}
+		}
+	case BulkBestEffort:
+		for i := 0; i < n; i++ {
+			var id int
+			e := backend.Transaction(m, func(tx *psql.Tx) (err error) {
+				id, err = action(tx, i)
+				return
+			})
+			if e == nil {
+				result.Ids = append(result.Ids, id)
+				continue
+			}
+			ierrs, ok := backend.inputErrorsWithIndex(e, i)
+			if !ok && isUniqueViolation(e) {
+				ierrs, ok = uniqueViolationWithIndex(m, e, i), true
+			}
+			if !ok {
+				err = e
+				return
+			}
+			result.Errors = append(result.Errors, ierrs...)
+		}
+	default:
+		err = NewInputErrors("Mode", "invalid")
+	}
+	return
+}
+
+// uniqueViolationWithIndex converts unique index violation of model m to
+// InputErrorsWithIndex.
+func uniqueViolationWithIndex(m *psql.Model, err error, index int) InputErrorsWithIndex {
+	field := uniqueViolationField(m, uniqueFields(reflect.TypeOf(m.New().Interface()).Elem()), err)
+	return InputErrorsWithIndex{{NewInputError(field, "unique"), index}}
+}
+
+// inputErrorsWithIndex converts input errors to InputErrorsWithIndex.
+func (backend Backend) inputErrorsWithIndex(err error, index int) (ierrs InputErrorsWithIndex, ok bool) {
+	if backend.IsErrNoRows(err) {
+		return InputErrorsWithIndex{{NewInputError("Id", "not_found"), index}}, true
+	}
+	switch errs := err.(type) {
+	case InputError:
+		return InputErrorsWithIndex{{errs, index}}, true
+	case InputErrors:
+		for _, e := range errs {
+			ierrs = append(ierrs, InputErrorWithIndex{e, index})
+		}
+		return ierrs, true
+	case validator.ValidationErrors:
+		for _, e := range errs {
+			ierrs = append(ierrs, InputErrorWithIndex{validatorFieldErrorToInputError(e), index})
+		}
+		return ierrs, true
+	}
+	return nil, false
+}
@@ -0,0 +1,269 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// cborEncoder writes values in CBOR, see RFC 8949.
+type cborEncoder struct {
+	bytes.Buffer
+}
+
+func (e *cborEncoder) writeNil() {
+	e.WriteByte(0xf6)
+}
+
+func (e *cborEncoder) writeBool(b bool) {
+	if b {
+		e.WriteByte(0xf5)
+	} else {
+		e.WriteByte(0xf4)
+	}
+}
+
+func (e *cborEncoder) writeInt(i int64) {
+	if i >= 0 {
+		e.writeHead(0, uint64(i))
+	} else {
+		e.writeHead(1, uint64(-1-i))
+	}
+}
+
+func (e *cborEncoder) writeUint(u uint64) {
+	e.writeHead(0, u)
+}
+
+func (e *cborEncoder) writeFloat(f float64) {
+	e.WriteByte(0xfb)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], math.Float64bits(f))
+	e.Write(b[:])
+}
+
+func (e *cborEncoder) writeString(s string) {
+	e.writeHead(3, uint64(len(s)))
+	e.WriteString(s)
+}
+
+func (e *cborEncoder) writeArrayHeader(n int) {
+	e.writeHead(4, uint64(n))
+}
+
+func (e *cborEncoder) writeMapHeader(n int) {
+	e.writeHead(5, uint64(n))
+}
+
+// writeHead writes initial byte of major type and argument u in the
+// shortest form.
+func (e *cborEncoder) writeHead(major byte, u uint64) {
+	major <<= 5
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u)
+	switch {
+	case u < 24:
+		e.WriteByte(major | byte(u))
+	case u <= math.MaxUint8:
+		e.Write([]byte{major | 24, byte(u)})
+	case u <= math.MaxUint16:
+		e.WriteByte(major | 25)
+		e.Write(b[6:])
+	case u <= math.MaxUint32:
+		e.WriteByte(major | 26)
+		e.Write(b[4:])
+	default:
+		e.WriteByte(major | 27)
+		e.Write(b[:])
+	}
+}
+
+// cborToJSON converts CBOR data item to JSON. Byte strings are converted to
+// base64 strings like []byte of encoding/json, epoch-based date/time (tag 1)
+// to RFC 3339 strings, other tags are ignored. Indefinite-length items are
+// supported.
+func cborToJSON(data []byte) ([]byte, error) {
+	d := &binaryDecoder{data: data}
+	var out bytes.Buffer
+	if err := d.cbor(&out, 0); err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, errors.New("cbor: extra data after value")
+	}
+	return out.Bytes(), nil
+}
+
+// errCBORBreak is returned by cbor() for the break stop code of
+// indefinite-length items.
+var errCBORBreak = errors.New("cbor: unexpected break")
+
+func (d *binaryDecoder) cbor(out *bytes.Buffer, depth int) error {
+	if depth > binaryMaxDepth {
+		return errors.New("cbor: max depth exceeded")
+	}
+	c, err := d.byte()
+	if err != nil {
+		return err
+	}
+	major, info := c>>5, c&0x1f
+	if c == 0xff {
+		return errCBORBreak
+	}
+	var u uint64
+	indefinite := info == 31
+	switch {
+	case info < 24:
+		u = uint64(info)
+	case info <= 27:
+		if u, err = d.uint(1 << (info - 24)); err != nil {
+			return err
+		}
+	case indefinite && major >= 2 && major <= 5:
+	default:
+		return errors.New("cbor: invalid additional information " + strconv.Itoa(int(info)))
+	}
+
+	switch major {
+	case 0:
+		out.WriteString(strconv.FormatUint(u, 10))
+	case 1:
+		if u > math.MaxInt64 {
+			return errors.New("cbor: negative integer overflow")
+		}
+		out.WriteString(strconv.FormatInt(-1-int64(u), 10))
+	case 2, 3:
+		var b []byte
+		if indefinite {
+			for {
+				c, err := d.byte()
+				if err != nil {
+					return err
+				}
+				if c == 0xff {
+					break
+				}
+				if c>>5 != major || c&0x1f > 27 {
+					return errors.New("cbor: invalid chunk of indefinite-length string")
+				}
+				n := uint64(c & 0x1f)
+				if n >= 24 {
+					if n, err = d.uint(1 << (n - 24)); err != nil {
+						return err
+					}
+				}
+				chunk, err := d.bytes(n)
+				if err != nil {
+					return err
+				}
+				b = append(b, chunk...)
+			}
+		} else if b, err = d.bytes(u); err != nil {
+			return err
+		}
+		var j []byte
+		if major == 2 {
+			j, _ = json.Marshal(b)
+		} else {
+			j, _ = json.Marshal(string(b))
+		}
+		out.Write(j)
+	case 4:
+		out.WriteByte('[')
+		for i := uint64(0); indefinite || i < u; i++ {
+			var item bytes.Buffer
+			err := d.cbor(&item, depth+1)
+			if err == errCBORBreak && indefinite {
+				break
+			} else if err != nil {
+				return err
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.Write(item.Bytes())
+		}
+		out.WriteByte(']')
+	case 5:
+		out.WriteByte('{')
+		for i := uint64(0); indefinite || i < u; i++ {
+			var key bytes.Buffer
+			err := d.cbor(&key, depth+1)
+			if err == errCBORBreak && indefinite {
+				break
+			} else if err != nil {
+				return err
+			}
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeJSONKey(out, key.Bytes()); err != nil {
+				return err
+			}
+			if err := d.cbor(out, depth+1); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case 6:
+		if u != 1 {
+			return d.cbor(out, depth+1) // ignore tag
+		}
+		var epoch bytes.Buffer
+		if err := d.cbor(&epoch, depth+1); err != nil {
+			return err
+		}
+		f, err := strconv.ParseFloat(epoch.String(), 64)
+		if err != nil {
+			return errors.New("cbor: invalid epoch-based date/time")
+		}
+		sec, frac := math.Modf(f)
+		t := time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		j, _ := json.Marshal(t.Format(time.RFC3339Nano))
+		out.Write(j)
+	case 7:
+		switch info {
+		case 20:
+			out.WriteString("false")
+		case 21:
+			out.WriteString("true")
+		case 22, 23:
+			out.WriteString("null")
+		case 25:
+			return writeJSONFloat(out, float16ToFloat64(uint16(u)))
+		case 26:
+			return writeJSONFloat(out, float64(math.Float32frombits(uint32(u))))
+		case 27:
+			return writeJSONFloat(out, math.Float64frombits(u))
+		default:
+			return errors.New("cbor: unsupported simple value " + strconv.FormatUint(u, 10))
+		}
+	}
+	return nil
+}
+
+// float16ToFloat64 converts IEEE 754 half-precision float to float64.
+func float16ToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
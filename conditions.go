@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gopsql/psql"
+)
+
+// Conditions is a list of SQL conditions joined with AND. Each "$?" in a
+// condition is a placeholder of one argument and is replaced with numbered
+// placeholders ($1, $2, ...) in the order the conditions are added.
+type Conditions struct {
+	conds []string
+	args  []interface{}
+}
+
+// Add appends condition and its arguments.
+func (c *Conditions) Add(cond string, args ...interface{}) {
+	c.conds = append(c.conds, cond)
+	c.args = append(c.args, args...)
+}
+
+// AddIn appends "column IN (...)" condition with values as arguments.
+func (c *Conditions) AddIn(column string, values ...interface{}) {
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = "$?"
+	}
+	c.Add(fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")), values...)
+}
+
+// Merge appends all conditions and arguments of other.
+func (c *Conditions) Merge(other Conditions) {
+	c.conds = append(c.conds, other.conds...)
+	c.args = append(c.args, other.args...)
+}
+
+// Len returns number of conditions.
+func (c Conditions) Len() int {
+	return len(c.conds)
+}
+
+// Args returns arguments of all conditions.
+func (c Conditions) Args() []interface{} {
+	return c.args
+}
+
+// String returns conditions joined with AND, with "$?" replaced with
+// numbered placeholders.
+func (c Conditions) String() string {
+	n := 0
+	conds := make([]string, len(c.conds))
+	for i, cond := range c.conds {
+		parts := strings.Split(cond, "$?")
+		var b strings.Builder
+		for j, part := range parts {
+			if j > 0 {
+				n++
+				fmt.Fprintf(&b, "$%d", n)
+			}
+			b.WriteString(part)
+		}
+		conds[i] = b.String()
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func isSQLite(m *psql.Model) bool {
+	return m.Connection() != nil && m.Connection().DriverName() == "sqlite"
+}
+
+func likeOperator(m *psql.Model) string {
+	if isSQLite(m) {
+		return "LIKE" // SQLite LIKE operator is case-insensitive
+	}
+	return "ILIKE"
+}
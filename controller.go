@@ -96,26 +96,47 @@ func (backend Backend) IsErrNoRows(err error) bool {
 }
 
 // HandleError returns status code and error message struct according to the
-// given error.
+// given error. Keys and names of the input errors are in the key case (see
+// SetKeyCase()).
 func (backend Backend) HandleError(err error) (status int, json interface{}) {
+	status, json = backend.handleError(err)
+	if backend.keyCase != KeyCaseGo {
+		json = casedJSON{json, backend.keyCase}
+	}
+	return
+}
+
+func (backend Backend) handleError(err error) (status int, json interface{}) {
 	if backend.IsErrNoRows(err) {
 		return 404, struct{ Message string }{"Not Found"}
 	}
 	switch errs := err.(type) {
 	case InputErrors:
-		return 400, map[string]interface{}{"Errors": errs}
+		ierrs := make(InputErrors, len(errs))
+		for i, e := range errs {
+			ierrs[i] = backend.keyCase.inputError(e)
+		}
+		return 400, map[string]interface{}{"Errors": ierrs}
 	case validator.ValidationErrors:
 		var ierrs InputErrors
 		for _, e := range errs {
-			ierrs = append(ierrs, validatorFieldErrorToInputError(e))
+			ierrs = append(ierrs, backend.keyCase.inputError(validatorFieldErrorToInputError(e)))
 		}
 		return 400, map[string]interface{}{"Errors": ierrs}
 	case ValidatorFieldErrors:
 		var ierrs []InputErrorWithIndex
 		for _, e := range errs {
-			ierrs = append(ierrs, InputErrorWithIndex{validatorFieldErrorToInputError(e.FieldError), e.Index})
+			ierrs = append(ierrs, InputErrorWithIndex{backend.keyCase.inputError(validatorFieldErrorToInputError(e.FieldError)), e.Index})
+		}
+		return 400, map[string]interface{}{"Errors": ierrs}
+	case InputErrorsWithIndex:
+		ierrs := make(InputErrorsWithIndex, len(errs))
+		for i, e := range errs {
+			ierrs[i] = InputErrorWithIndex{backend.keyCase.inputError(e.InputError), e.Index}
 		}
 		return 400, map[string]interface{}{"Errors": ierrs}
+	case PreconditionFailedError:
+		return 412, errs.Current
 	}
 	backend.logger.Error("Server Error:", err)
 	return 500, struct{ Message string }{"Server Error"}
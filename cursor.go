@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gopsql/pagination/v2"
+	"github.com/gopsql/psql"
+)
+
+type (
+	// CursorPagination contains options of cursor (keyset) pagination.
+	// Rows are sorted by Column and then by id, and After or Before is
+	// the opaque cursor returned by a previous query.
+	CursorPagination struct {
+		Per    int
+		Sort   string // name of the sort, a key of AllowedSorts
+		Column string // sort column, a value of AllowedSorts
+		Desc   bool
+		After  string
+		Before string
+	}
+
+	// CursorPaginationResult contains cursors of next page and previous
+	// page. Cursor is empty if there are no more rows.
+	CursorPaginationResult struct {
+		Per   int
+		Sort  string
+		Order string
+		Next  string
+		Prev  string
+	}
+
+	cursor struct {
+		Sort  string          `json:"s"`
+		Order string          `json:"o"`
+		Value json.RawMessage `json:"v"`
+		Id    json.RawMessage `json:"i"`
+	}
+)
+
+// NewFiberCursorPagination returns cursor pagination options from the query
+// string (sort, order, after and before) of a fiber context, using the
+// AllowedSorts, DefaultSort and DefaultOrder of sort.
+func NewFiberCursorPagination(c FiberCtx, sort pagination.Sort, per int) CursorPagination {
+	name := c.Query("sort")
+	column, ok := sort.AllowedSorts[name]
+	if !ok {
+		name = sort.DefaultSort
+		column = sort.AllowedSorts[name]
+	}
+	order := strings.ToLower(c.Query("order"))
+	if order != "asc" && order != "desc" {
+		order = strings.ToLower(sort.DefaultOrder)
+	}
+	return CursorPagination{
+		Per:    per,
+		Sort:   name,
+		Column: column,
+		Desc:   order == "desc",
+		After:  c.Query("after"),
+		Before: c.Query("before"),
+	}
+}
+
+func (p CursorPagination) order() string {
+	if p.Desc {
+		return "desc"
+	}
+	return "asc"
+}
+
+// FindWithCursor finds rows of model m matching where using cursor pagination
+// and puts them into target, which should be a pointer to slice created by
+// NewSlice() of the model. Unlike LIMIT and OFFSET, cursor pagination stays
+// fast on large tables and does not skip or duplicate rows when rows are
+// inserted concurrently. Invalid cursor results in InputErrors.
+func (backend Backend) FindWithCursor(m *psql.Model, where Conditions, p CursorPagination, target reflect.Value) (result CursorPaginationResult, err error) {
+	result = CursorPaginationResult{Per: p.Per, Sort: p.Sort, Order: p.order()}
+	var conds Conditions
+	conds.Merge(where)
+	field, ok := fieldByColumn(m, p.Column)
+	if !ok {
+		err = fmt.Errorf("no field for column %s", p.Column)
+		return
+	}
+	idColumn := m.ToColumnName("Id")
+
+	backward := p.Before != ""
+	token := p.After
+	if backward {
+		token = p.Before
+	}
+	if token != "" {
+		var value, id interface{}
+		value, id, err = p.decode(m, field, token)
+		if err != nil {
+			return
+		}
+		op := ">"
+		if p.Desc != backward {
+			op = "<"
+		}
+		conds.Add(fmt.Sprintf("(%[1]s %[2]s $? OR (%[1]s = $? AND %[3]s %[2]s $?))", p.Column, op, idColumn), value, value, id)
+	}
+
+	dir := "ASC"
+	if p.Desc != backward {
+		dir = "DESC"
+	}
+	err = m.Find().Where(conds.String(), conds.Args()...).
+		OrderBy(fmt.Sprintf("%s %s, %s %s", p.Column, dir, idColumn, dir)).
+		Limit(p.Per + 1).Query(target.Interface())
+	if err != nil {
+		return
+	}
+
+	rows := target.Elem()
+	hasMore := rows.Len() > p.Per
+	if hasMore {
+		rows.Set(rows.Slice(0, p.Per))
+	}
+	if backward {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	if rows.Len() == 0 {
+		return
+	}
+	first, last := rows.Index(0), rows.Index(rows.Len()-1)
+	if hasMore || backward {
+		result.Next = p.encode(last.FieldByName(field.Name), last.FieldByName("Id"))
+	}
+	if (hasMore && backward) || (!backward && token != "") {
+		result.Prev = p.encode(first.FieldByName(field.Name), first.FieldByName("Id"))
+	}
+	return
+}
+
+func (p CursorPagination) encode(value, id reflect.Value) string {
+	v, _ := json.Marshal(value.Interface())
+	i, _ := json.Marshal(id.Interface())
+	b, _ := json.Marshal(cursor{p.Sort, p.order(), v, i})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (p CursorPagination) decode(m *psql.Model, field reflect.StructField, token string) (value, id interface{}, err error) {
+	invalid := NewInputErrors("Cursor", "invalid")
+	b, e := base64.RawURLEncoding.DecodeString(token)
+	if e != nil {
+		err = invalid
+		return
+	}
+	var c cursor
+	if json.Unmarshal(b, &c) != nil || c.Sort != p.Sort || c.Order != p.order() {
+		err = invalid
+		return
+	}
+	idField, _ := reflect.TypeOf(m.New().Interface()).Elem().FieldByName("Id")
+	v := reflect.New(field.Type)
+	i := reflect.New(idField.Type)
+	if json.Unmarshal(c.Value, v.Interface()) != nil || json.Unmarshal(c.Id, i.Interface()) != nil {
+		err = invalid
+		return
+	}
+	return v.Elem().Interface(), i.Elem().Interface(), nil
+}
+
+// fieldByColumn finds struct field of model m by column name.
+func fieldByColumn(m *psql.Model, column string) (reflect.StructField, bool) {
+	typ := reflect.TypeOf(m.New().Interface()).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		if f := typ.Field(i); m.ToColumnName(f.Name) == column {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -1,6 +1,9 @@
 package backend
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 type (
 	// InputError collection.
@@ -20,6 +23,9 @@ type (
 		InputError
 		Index int
 	}
+
+	// InputErrorWithIndex collection.
+	InputErrorsWithIndex []InputErrorWithIndex
 )
 
 func (err InputError) Error() string {
@@ -34,6 +40,14 @@ func (errs InputErrors) Error() string {
 	return "Errors: " + strings.Join(msgs, ", ")
 }
 
+func (errs InputErrorsWithIndex) Error() string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, fmt.Sprintf("%d.%s", err.Index, err.Error()))
+	}
+	return "Errors: " + strings.Join(msgs, ", ")
+}
+
 func (errs InputErrors) PanicIfPresent() {
 	if len(errs) > 0 {
 		panic(errs)
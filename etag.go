@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// HasETag can be implemented by models to provide their own entity
+	// tags instead of the ones derived from Version or UpdatedAt.
+	HasETag interface {
+		ETag() string
+	}
+
+	// PreconditionFailedError is returned if the If-Match header of a
+	// request does not match the entity tag of the current record.
+	// HandleError responds status 412 with Current, which is the current
+	// representation of the record. ETag is the entity tag of the current
+	// record.
+	PreconditionFailedError struct {
+		Current interface{}
+		ETag    string
+	}
+)
+
+func (PreconditionFailedError) Error() string {
+	return "Precondition Failed"
+}
+
+// ETag returns entity tag of record. If record does not implement HasETag,
+// the tag is derived from its Id and Version field, or Id and UpdatedAt field
+// if record has no Version field. Empty string is returned if record has
+// neither of them.
+func ETag(record interface{}) string {
+	if r, ok := record.(HasETag); ok {
+		return r.ETag()
+	}
+	v := reflect.Indirect(reflect.ValueOf(record))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	var version string
+	if f := v.FieldByName("Version"); f.IsValid() {
+		version = fmt.Sprint(f.Interface())
+	} else if t, ok := fieldInterface(v, "UpdatedAt").(time.Time); ok {
+		version = strconv.FormatInt(t.UnixNano(), 36)
+	} else {
+		return ""
+	}
+	return fmt.Sprintf(`"%v-%s"`, fieldInterface(v, "Id"), version)
+}
+
+// FiberSetETag sets ETag header of fiber context to entity tag of record.
+func FiberSetETag(c FiberCtx, record interface{}) {
+	if tag := ETag(record); tag != "" {
+		c.Set("ETag", tag)
+	}
+}
+
+// FiberCheckIfMatch returns PreconditionFailedError containing the "show"
+// view of the current record if the If-Match header of fiber context is
+// present and does not match entity tag of the current record. Tags are
+// compared with the strong comparison of RFC 7232, weak tags never match.
+func FiberCheckIfMatch(c FiberCtx, current interface{}) error {
+	ifMatch := c.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+	tag := ETag(current)
+	for _, t := range strings.Split(ifMatch, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || t == tag {
+			return nil
+		}
+	}
+	FiberSetETag(c, current)
+	return PreconditionFailedError{serialize(current, "show"), tag}
+}
+
+// fiberSetErrorETag sets ETag header of fiber context to entity tag of the
+// latest record if err is PreconditionFailedError, and returns err.
+func fiberSetErrorETag(c FiberCtx, err error) error {
+	if e, ok := err.(PreconditionFailedError); ok && e.ETag != "" {
+		c.Set("ETag", e.ETag)
+	}
+	return err
+}
+
+func fieldInterface(v reflect.Value, name string) interface{} {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if f := v.FieldByName(name); f.IsValid() && f.CanInterface() {
+		return f.Interface()
+	}
+	return nil
+}
+
+func toInt64(i interface{}) (int64, bool) {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	}
+	return 0, false
+}
@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gopsql/psql"
+)
+
+// Export formats and their content types.
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ndjson": "application/x-ndjson",
+}
+
+// Number of rows queried at a time when exporting.
+const exportBatchSize = 500
+
+// FiberExport streams all rows of model m matching where, sorted by the sort
+// column of p, as a CSV, XLSX or NDJSON file. Rows are serialized with the
+// "list" view if the model implements Serializable, write-only fields are
+// left out, and the fields of the serialized new record of the model become
+// the columns of CSV and XLSX files, so exports without rows still have the
+// header. Strings of CSV files starting with =, +, -, @, tab or carriage
+// return are prefixed with a single quote, so that spreadsheet applications
+// do not run them as formulas. Rows are queried in batches using cursor
+// pagination, so exporting large tables does not load all rows into memory.
+//
+// The first batch is queried before the response starts, so errors like
+// invalid sort column are returned with the proper status. Once streaming has
+// started the status can no longer be changed: errors of later batches are
+// logged and abort the stream, so the client receives a truncated file and
+// the connection is closed without the terminating chunk.
+func (backend Backend) FiberExport(c FiberCtx, m *psql.Model, where Conditions, p CursorPagination, format string) error {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return NewInputErrors("Format", "invalid")
+	}
+	p.Per = exportBatchSize
+	p.After, p.Before = "", ""
+	rows := m.NewSlice()
+	result, err := backend.FindWithCursor(m, where, p, rows)
+	if err != nil {
+		return err
+	}
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, m.TableName(), format))
+	r, w := io.Pipe()
+	go func() {
+		err := backend.export(w, m, where, p, format, rows, result)
+		if err != nil {
+			backend.logger.Error("Export Error:", err)
+		}
+		w.CloseWithError(err)
+	}()
+	return c.SendStream(r)
+}
+
+// export writes rows of the first batch and the batches after it to w.
+func (backend Backend) export(w io.Writer, m *psql.Model, where Conditions, p CursorPagination, format string, rows reflect.Value, result CursorPaginationResult) error {
+	buf := bufio.NewWriter(w)
+	var write func(keys []string, values []json.RawMessage) error
+	var finish func() error
+	columns, err := exportColumns(m)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(buf)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		write = func(keys []string, values []json.RawMessage) error {
+			record := make([]string, len(columns))
+			for i, value := range exportValues(columns, keys, values) {
+				record[i] = exportCSVString(value)
+			}
+			return cw.Write(record)
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case "xlsx":
+		xw, err := newXLSXWriter(buf)
+		if err != nil {
+			return err
+		}
+		header := make([]interface{}, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		if err := xw.Write(header); err != nil {
+			return err
+		}
+		write = func(keys []string, values []json.RawMessage) error {
+			cells := make([]interface{}, len(columns))
+			for i, value := range exportValues(columns, keys, values) {
+				var f float64
+				if len(value) > 0 && value[0] != '"' && string(value) != "null" && json.Unmarshal(value, &f) == nil {
+					cells[i] = f
+				} else {
+					cells[i] = exportString(value)
+				}
+			}
+			return xw.Write(cells)
+		}
+		finish = xw.Close
+	case "ndjson":
+		write = func(keys []string, values []json.RawMessage) error {
+			b, _ := jsonObject{keys, values}.MarshalJSON()
+			buf.Write(b)
+			return buf.WriteByte('\n')
+		}
+		finish = func() error { return nil }
+	}
+
+	for {
+		for i := 0; i < rows.Elem().Len(); i++ {
+			elem := serialize(rows.Elem().Index(i).Addr().Interface(), "list")
+			b, err := json.Marshal(elem)
+			if err != nil {
+				return err
+			}
+			keys, values, err := jsonObjectFields(b)
+			if err != nil {
+				return err
+			}
+			if err := write(keys, values); err != nil {
+				return err
+			}
+		}
+		if result.Next == "" {
+			break
+		}
+		p.After = result.Next
+		rows = m.NewSlice()
+		result, err = backend.FindWithCursor(m, where, p, rows)
+		if err != nil {
+			return err
+		}
+	}
+	if err := finish(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// exportColumns returns the fields of the serialized new record of model m.
+func exportColumns(m *psql.Model) ([]string, error) {
+	b, err := json.Marshal(serialize(m.New().Interface(), "list"))
+	if err != nil {
+		return nil, err
+	}
+	columns, _, err := jsonObjectFields(b)
+	return columns, err
+}
+
+// exportValues returns values in the order of columns.
+func exportValues(columns, keys []string, values []json.RawMessage) []json.RawMessage {
+	out := make([]json.RawMessage, len(columns))
+	for i, column := range columns {
+		if i < len(keys) && keys[i] == column {
+			out[i] = values[i]
+			continue
+		}
+		for j, key := range keys {
+			if key == column {
+				out[i] = values[j]
+				break
+			}
+		}
+	}
+	return out
+}
+
+// exportString converts JSON value to string: strings are unquoted, null
+// becomes empty string, other values are kept as JSON.
+func exportString(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var s string
+	if value[0] == '"' && json.Unmarshal(value, &s) == nil {
+		return s
+	}
+	return string(value)
+}
+
+// exportCSVString converts JSON value to string like exportString, strings
+// that spreadsheet applications would run as formulas are prefixed with a
+// single quote.
+func exportCSVString(value json.RawMessage) string {
+	s := exportString(value)
+	if len(value) > 0 && value[0] == '"' && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// jsonObjectFields returns keys and values of a JSON object in their original
+// order.
+func jsonObjectFields(b []byte) (keys []string, values []json.RawMessage, err error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, nil, fmt.Errorf("not a JSON object: %s", strings.TrimSpace(string(b)))
+	}
+	for dec.More() {
+		var t json.Token
+		t, err = dec.Token()
+		if err != nil {
+			return
+		}
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return
+		}
+		keys = append(keys, t.(string))
+		values = append(values, value)
+	}
+	return
+}
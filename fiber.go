@@ -2,6 +2,7 @@ package backend
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"time"
@@ -18,11 +19,16 @@ type (
 		IP() string
 		JSON(data interface{}, ctype ...string) error
 		Locals(key interface{}, value ...interface{}) (val interface{})
+		Method(override ...string) string
 		Next() (err error)
 		Params(key string, defaultValue ...string) string
+		Protocol() string
+		Queries() map[string]string
 		Query(key string, defaultValue ...string) string
 		QueryParser(out interface{}) error
 		SendStatus(status int) error
+		SendStream(stream io.Reader, size ...int) error
+		Set(key string, val string)
 	}
 
 	FiberHandler func(FiberCtx) error
@@ -86,13 +92,19 @@ func (backend Backend) MustFiberDeleteSession(c FiberCtx) {
 	}
 }
 
-func (backend Backend) MustFiberValidateNewSession(c FiberCtx) string {
+// FiberValidateNewSession validates the name and password in the request
+// body and creates new session of the admin, returning a new JWT string. The
+// admin must be member of the tenant resolved by subdomain or header, see
+// FiberTenantId().
+func (backend Backend) FiberValidateNewSession(c FiberCtx) (string, error) {
 	var req struct {
 		Name     string `validate:"gt=0,lte=30"`
 		Password string `validate:"gte=6,lte=72"`
 	}
 	c.BodyParser(&req)
-	backend.MustValidateStruct(req)
+	if err := backend.ValidateStruct(req); err != nil {
+		return "", err
+	}
 	var id int
 	var password bcrypt.Password
 	var deletedAt *time.Time
@@ -101,18 +113,46 @@ func (backend Backend) MustFiberValidateNewSession(c FiberCtx) string {
 		Where(fmt.Sprintf("lower(%s) = $1", m.ToColumnName("Name")), strings.ToLower(req.Name)).
 		QueryRow(&id, &password, &deletedAt)
 	if err != nil || !password.Equal(req.Password) {
-		panic(NewInputErrors("Password", "wrong"))
+		return "", NewInputErrors("Password", "wrong")
 	}
 	if deletedAt != nil {
-		panic(NewInputErrors("Name", "deleted"))
+		return "", NewInputErrors("Name", "deleted")
+	}
+	tenantId, err := backend.FiberTenantId(c)
+	if err != nil {
+		return "", err
 	}
-	return backend.MustFiberNewSession(c, id)
+	if tenantId != 0 {
+		member, err := backend.IsTenantMember(c, tenantId, id)
+		if err != nil {
+			return "", err
+		}
+		if !member {
+			return "", NewInputErrors("Password", "wrong")
+		}
+	}
+	return backend.FiberNewSession(c, id)
+}
+
+// MustFiberValidateNewSession is like FiberValidateNewSession but panics if
+// validation or session creation fails.
+func (backend Backend) MustFiberValidateNewSession(c FiberCtx) string {
+	token, err := backend.FiberValidateNewSession(c)
+	if err != nil {
+		panic(err)
+	}
+	return token
 }
 
 // FiberGetAdminAndSessionId returns the admin and session ID from the
-// Authorization header of a fiber context.
+// Authorization header of a fiber context, or from the "Authorization" local
+// set by the HTML controllers from the session cookie.
 func (backend Backend) FiberGetAdminAndSessionId(c FiberCtx) (adminId int, sessionId string, ok bool) {
-	id, sessionId, ok := backend.jwtSession.ParseAuthorization(c.Get("Authorization"))
+	auth := c.Get("Authorization")
+	if local, isString := c.Locals("Authorization").(string); isString && auth == "" {
+		auth = local
+	}
+	id, sessionId, ok := backend.jwtSession.ParseAuthorization(auth)
 	if adminId, err := strconv.Atoi(id); err == nil {
 		return adminId, sessionId, ok
 	}
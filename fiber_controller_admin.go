@@ -1,16 +1,18 @@
 package backend
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gopsql/pagination/v2"
 	"github.com/gopsql/psql"
 )
 
-// NewFiberAdminsCtrl creates a simple admins controller for fiber.
+// NewFiberAdminsCtrl creates a simple admins controller for fiber. If the
+// Tenant model is added, admins are limited to the members of the tenant of
+// the request, and created admins become its members.
 func (backend *Backend) NewFiberAdminsCtrl() *fiberAdminsCtrl {
 	return &fiberAdminsCtrl{
 		backend: backend,
@@ -24,71 +26,46 @@ type fiberAdminsCtrl struct {
 func (ctrl fiberAdminsCtrl) List(c FiberCtx) error {
 	mAdmins := ctrl.backend.ModelByName(getName(c, "Admin"))
 
-	q := pagination.PaginationQuerySort{
-		pagination.Pagination{
-			MaxPer:     50,
-			DefaultPer: 20,
-		},
-		pagination.Query{},
-		pagination.Sort{
-			AllowedSorts: map[string]string{
-				"name":       mAdmins.ToColumnName("Name"),
-				"created_at": mAdmins.ToColumnName("CreatedAt"),
-			},
-			DefaultSort:  "created_at",
-			DefaultOrder: "asc",
-		},
-	}
-	pagination.Bind(&q, c.QueryParser)
+	q, sort := ctrl.listQuery(c, mAdmins)
 
-	var cond []string
-	var args []interface{}
-	if pattern := q.GetLikePattern(); pattern != "" {
-		var like string
-		if mAdmins.Connection() != nil && mAdmins.Connection().DriverName() == "sqlite" {
-			like = "LIKE" // SQLite LIKE operator is case-insensitive
-		} else {
-			like = "ILIKE"
-		}
-		cond = append(cond, fmt.Sprintf("%s %s $?", mAdmins.ToColumnName("Name"), like))
-		args = append(args, pattern)
-	}
-	if c.Query("status") == "deleted" {
-		cond = append(cond, fmt.Sprintf("%s IS NOT NULL", mAdmins.ToColumnName("DeletedAt")))
-	} else {
-		cond = append(cond, fmt.Sprintf("%s IS NULL", mAdmins.ToColumnName("DeletedAt")))
-	}
-
-	var sql string
-	if len(cond) > 0 {
-		for i := range cond {
-			cond[i] = strings.Replace(cond[i], "$?", fmt.Sprintf("$%d", i+1), -1)
-		}
-		sql = strings.Join(cond, " AND ")
+	conds, rank, err := ctrl.listConditions(c, mAdmins, q.GetLikePattern())
+	if err != nil {
+		return err
 	}
 
-	count := mAdmins.Where(sql, args...).MustCount()
-	admins := mAdmins.NewSlice()
-	mAdmins.Find().Where(sql, args...).OrderBy(q.OrderByValue()).Limit(q.Limit()).Offset(q.Offset()).MustQuery(admins.Interface())
-
 	ret := struct {
 		Admins        []interface{}
 		SessionsCount map[int]int
-		Pagination    pagination.PaginationQuerySortResult
-	}{Pagination: q.PaginationQuerySortResult(count)}
+		Pagination    interface{}
+	}{}
+
+	admins := mAdmins.NewSlice()
+	if c.Query("pagination") == "cursor" {
+		p := NewFiberCursorPagination(c, sort, q.Limit())
+		result, err := ctrl.backend.FindWithCursor(mAdmins, conds, p, admins)
+		if err != nil {
+			return err
+		}
+		ret.Pagination = result
+	} else {
+		sql := conds.String()
+		count := mAdmins.Where(sql, conds.Args()...).MustCount()
+		mAdmins.Find().Where(sql, conds.Args()...).OrderBy(orderByRank(c, q, rank)).Limit(q.Limit()).Offset(q.Offset()).MustQuery(admins.Interface())
+		ret.Pagination = q.PaginationQuerySortResult(count)
+	}
 
 	var ids []string
+	var elems []interface{}
 	for i := 0; i < admins.Elem().Len(); i++ {
 		elem := admins.Elem().Index(i).Addr().Interface()
-		if admin, ok := elem.(Serializable); ok {
-			ret.Admins = append(ret.Admins, admin.Serialize("list"))
-		} else {
-			ret.Admins = append(ret.Admins, elem)
-		}
+		elems = append(elems, elem)
 		if admin, ok := elem.(IsAdmin); ok {
 			ids = append(ids, strconv.Itoa(admin.GetId()))
 		}
 	}
+	if ret.Admins, err = ctrl.backend.FiberSerialize(c, mAdmins, "list", elems...); err != nil {
+		return err
+	}
 
 	if len(ids) > 0 {
 		adminId := getName(c, "AdminId")
@@ -101,72 +78,392 @@ func (ctrl fiberAdminsCtrl) List(c FiberCtx) error {
 	return c.JSON(ret)
 }
 
+// Export streams all admins matching the search, status and filters of the
+// list endpoint, ignoring pagination, as CSV, XLSX or NDJSON file according
+// to the format param or query.
+func (ctrl fiberAdminsCtrl) Export(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	q, sort := ctrl.listQuery(c, m)
+	conds, _, err := ctrl.listConditions(c, m, q.GetLikePattern())
+	if err != nil {
+		return err
+	}
+	format := c.Params("format", c.Query("format"))
+	return ctrl.backend.FiberExport(c, m, conds, NewFiberCursorPagination(c, sort, q.Limit()), format)
+}
+
+// listQuery returns the pagination, search query and sort of the list
+// endpoint bound from the query string.
+func (ctrl fiberAdminsCtrl) listQuery(c FiberCtx, m *psql.Model) (pagination.PaginationQuerySort, pagination.Sort) {
+	sort := pagination.Sort{
+		AllowedSorts: map[string]string{
+			"name":       m.ToColumnName("Name"),
+			"created_at": m.ToColumnName("CreatedAt"),
+		},
+		DefaultSort:  "created_at",
+		DefaultOrder: "asc",
+	}
+	q := pagination.PaginationQuerySort{
+		pagination.Pagination{
+			MaxPer:     50,
+			DefaultPer: 20,
+		},
+		pagination.Query{},
+		sort,
+	}
+	pagination.Bind(&q, c.QueryParser)
+	return q, sort
+}
+
+// listConditions returns conditions of the tenant, search query, status and
+// filters of the list endpoint, and the ORDER BY expression of search
+// relevance. Models that are not Searchable are searched by name with the
+// LIKE pattern.
+func (ctrl fiberAdminsCtrl) listConditions(c FiberCtx, m *psql.Model, pattern string) (conds Conditions, rank string, err error) {
+	if conds, err = ctrl.backend.FiberTenantConditions(c, m); err != nil {
+		return
+	}
+	if _, ok := m.New().Interface().(Searchable); ok {
+		rank = ctrl.backend.AddSearch(&conds, m, c.Query("query"))
+	} else if pattern != "" {
+		conds.Add(fmt.Sprintf("%s %s $?", m.ToColumnName("Name"), likeOperator(m)), pattern)
+	}
+	if c.Query("status") == "deleted" {
+		conds.Add(fmt.Sprintf("%s IS NOT NULL", m.ToColumnName("DeletedAt")))
+	} else {
+		conds.Add(fmt.Sprintf("%s IS NULL", m.ToColumnName("DeletedAt")))
+	}
+	filters, err := ctrl.backend.FiberFilterConditions(c, m)
+	if err != nil {
+		return
+	}
+	conds.Merge(filters)
+	return
+}
+
 func (ctrl fiberAdminsCtrl) Show(c FiberCtx) error {
 	m := ctrl.backend.ModelByName(getName(c, "Admin"))
 	admin := m.New().Interface()
-	m.Find().WHERE("Id", "=", c.Params("id")).MustQuery(admin)
-	if u, ok := admin.(Serializable); ok {
-		return c.JSON(u.Serialize("show"))
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), admin); err != nil {
+		return err
 	}
-	return c.JSON(admin)
+	FiberSetETag(c, admin)
+	return ctrl.backend.fiberShow(c, m, admin)
 }
 
 func (ctrl fiberAdminsCtrl) Create(c FiberCtx) error {
 	m := ctrl.backend.ModelByName(getName(c, "Admin"))
 	admin := m.New().Interface()
 	if c.Get("Content-Length") == "0" {
-		if u, ok := admin.(Serializable); ok {
-			return c.JSON(u.Serialize("show"))
-		}
-		return c.JSON(admin)
+		return c.JSON(serialize(admin, "show"))
+	}
+	params := ctrl.params(c, "create")
+	body, err := ctrl.backend.FiberInput(c, admin, params)
+	if err != nil {
+		return err
 	}
-	var id int
 	changes := m.MustAssign(
 		admin,
-		m.Permit(ctrl.params(c, "create")...).Filter(c.Body()),
+		m.Permit(params...).Filter(body),
 		m.CreatedAt(),
 		m.UpdatedAt(),
 	)
-	ctrl.backend.MustValidateStruct(admin)
-	m.Insert(changes...).Returning(m.ToColumnName("Id")).MustQueryRow(&id)
-	m.Find().WHERE("Id", "=", id).MustQuery(admin)
-	return c.JSON(admin)
+	err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		if err := ctrl.backend.CreateInTransaction(tx, m, admin, changes); err != nil {
+			return err
+		}
+		return ctrl.backend.fiberAddTenantMemberInTransaction(c, tx, m, admin)
+	})
+	if err != nil {
+		return err
+	}
+	FiberSetETag(c, admin)
+	return ctrl.backend.fiberShow(c, m, admin)
 }
 
+// Update loads the admin, applies the request body as JSON merge patch
+// (PATCH) or full replacement of the permitted params (PUT), validates the
+// result and saves the changed fields. Like Create, form-urlencoded and
+// multipart bodies are accepted, see FiberInput().
 func (ctrl fiberAdminsCtrl) Update(c FiberCtx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	m := ctrl.backend.ModelByName(getName(c, "Admin"))
-	admin := m.New().Interface()
-	if admin, ok := admin.(IsAdmin); ok {
-		admin.SetId(id)
+	current := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, id, current); err != nil {
+		return err
 	}
-	changes := m.MustAssign(
-		admin,
-		m.Permit(ctrl.params(c, "update")...).Filter(c.Body()),
-		m.UpdatedAt(),
-	)
-	ctrl.backend.MustValidateStruct(admin)
-	m.Update(changes...).WHERE("Id", "=", id).MustExecute()
-	m.Find().WHERE("Id", "=", id).MustQuery(admin)
-	return c.JSON(admin)
+	if err := FiberCheckIfMatch(c, current); err != nil {
+		return err
+	}
+	params := ctrl.params(c, "update")
+	body, err := ctrl.backend.FiberInput(c, current, params)
+	if err != nil {
+		return err
+	}
+	admin, changes, err := ctrl.backend.AssignUpdate(m, current, body, params, c.Method() == "PUT")
+	if err != nil {
+		return err
+	}
+	err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.UpdateInTransaction(tx, m, current, admin, changes)
+	})
+	if err != nil {
+		return fiberSetErrorETag(c, err)
+	}
+	FiberSetETag(c, admin)
+	return ctrl.backend.fiberShow(c, m, admin)
 }
 
 func (ctrl fiberAdminsCtrl) Restore(c FiberCtx) error {
-	ctrl.backend.ModelByName(getName(c, "Admin")).Update("DeletedAt", nil).WHERE("Id", "=", c.Params("id")).MustExecute()
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), admin); err != nil {
+		return err
+	}
+	err := ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.RestoreInTransaction(tx, m, admin)
+	})
+	if err != nil {
+		return fiberSetErrorETag(c, err)
+	}
 	return ctrl.Show(c)
 }
 
+// Destroy soft deletes the admin and deletes its sessions.
 func (ctrl fiberAdminsCtrl) Destroy(c FiberCtx) error {
-	ctrl.backend.ModelByName(getName(c, "Admin")).
-		Update("DeletedAt", time.Now().UTC().Truncate(time.Second)).
-		WHERE("Id", "=", c.Params("id")).MustExecute()
-	ctrl.backend.ModelByName(getName(c, "AdminSession")).
-		Delete().WHERE(getName(c, "AdminId"), "=", c.Params("id")).MustExecute()
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), admin); err != nil {
+		return err
+	}
+	if err := FiberCheckIfMatch(c, admin); err != nil {
+		return err
+	}
+	mSessions := ctrl.backend.ModelByName(getName(c, "AdminSession"))
+	err := ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		if err := ctrl.backend.DestroyInTransaction(tx, m, admin, false); err != nil {
+			return err
+		}
+		return mSessions.Delete().WHERE(getName(c, "AdminId"), "=", c.Params("id")).ExecuteInTransaction(tx)
+	})
+	if err != nil {
+		return err
+	}
 	return ctrl.Show(c)
 }
 
+// Purge deletes the admin and its sessions permanently. Current admin cannot
+// delete itself.
+func (ctrl fiberAdminsCtrl) Purge(c FiberCtx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	if admin, ok := ctrl.backend.FiberGetCurrentAdmin(c).(IsAdmin); ok && admin.GetId() == id {
+		return NewInputErrors("Id", "self")
+	}
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, id, admin); err != nil {
+		return err
+	}
+	if err := FiberCheckIfMatch(c, admin); err != nil {
+		return err
+	}
+	if err := ctrl.backend.DeletePermanently(m, id); err != nil {
+		return err
+	}
+	return c.SendStatus(204)
+}
+
+// Import inserts admins from JSON array or CSV, see FiberImport().
+func (ctrl fiberAdminsCtrl) Import(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	result, err := ctrl.backend.FiberImport(c, m, ctrl.params(c, "create"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// BulkUpdate updates multiple admins. Request body contains Items, each item
+// contains Id and the permitted params, and optional Mode, see Bulk(). Each
+// item is applied to the admin as JSON merge patch.
+func (ctrl fiberAdminsCtrl) BulkUpdate(c FiberCtx) error {
+	var req BulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return NewInputErrors("Items", "invalid")
+	}
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	params := ctrl.params(c, "update")
+	result, err := ctrl.backend.Bulk(m, req.Mode, len(req.Items), func(tx *psql.Tx, i int) (id int, err error) {
+		var item struct{ Id int }
+		if json.Unmarshal(req.Items[i], &item) != nil || item.Id == 0 {
+			err = NewInputErrors("Id", "invalid")
+			return
+		}
+		current := m.New().Interface()
+		if err = ctrl.backend.fiberFindInTransaction(c, tx, m, item.Id, current); err != nil {
+			return
+		}
+		admin, changes, err := ctrl.backend.AssignUpdate(m, current, req.Items[i], params, false)
+		if err != nil {
+			return
+		}
+		if err = ctrl.backend.UpdateInTransaction(tx, m, current, admin, changes); err != nil {
+			return
+		}
+		id = item.Id
+		return
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// BulkRestore restores multiple deleted admins. Request body contains Ids
+// and optional Mode, see Bulk().
+func (ctrl fiberAdminsCtrl) BulkRestore(c FiberCtx) error {
+	var req BulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return NewInputErrors("Ids", "invalid")
+	}
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	result, err := ctrl.backend.Bulk(m, req.Mode, len(req.Ids), func(tx *psql.Tx, i int) (id int, err error) {
+		admin := m.New().Interface()
+		if err = ctrl.backend.fiberFindInTransaction(c, tx, m, req.Ids[i], admin); err != nil {
+			return
+		}
+		if err = ctrl.backend.RestoreInTransaction(tx, m, admin); err != nil {
+			return
+		}
+		id = req.Ids[i]
+		return
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// BulkDestroy deletes multiple admins and their sessions. Request body
+// contains Ids and optional Mode, see Bulk().
+func (ctrl fiberAdminsCtrl) BulkDestroy(c FiberCtx) error {
+	var req BulkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return NewInputErrors("Ids", "invalid")
+	}
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	mSessions := ctrl.backend.ModelByName(getName(c, "AdminSession"))
+	result, err := ctrl.backend.Bulk(m, req.Mode, len(req.Ids), func(tx *psql.Tx, i int) (id int, err error) {
+		admin := m.New().Interface()
+		if err = ctrl.backend.fiberFindInTransaction(c, tx, m, req.Ids[i], admin); err != nil {
+			return
+		}
+		if err = ctrl.backend.DestroyInTransaction(tx, m, admin, false); err != nil {
+			return
+		}
+		id = req.Ids[i]
+		err = mSessions.Delete().WHERE(getName(c, "AdminId"), "=", id).ExecuteInTransaction(tx)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// Sessions lists sessions of the admin, most recently used first by
+// default. Current is the Id of the session of the current request.
+func (ctrl fiberAdminsCtrl) Sessions(c FiberCtx) error {
+	adminId, err := ctrl.adminId(c)
+	if err != nil {
+		return err
+	}
+
+	m := ctrl.backend.ModelByName(getName(c, "AdminSession"))
+	q := pagination.PaginationQuerySort{
+		pagination.Pagination{
+			MaxPer:     50,
+			DefaultPer: 20,
+		},
+		pagination.Query{},
+		pagination.Sort{
+			AllowedSorts: map[string]string{
+				"created_at": m.ToColumnName("CreatedAt"),
+				"updated_at": m.ToColumnName("UpdatedAt"),
+			},
+			DefaultSort:  "updated_at",
+			DefaultOrder: "desc",
+		},
+	}
+	pagination.Bind(&q, c.QueryParser)
+
+	sql := fmt.Sprintf("%s = $1", m.ToColumnName(getName(c, "AdminId")))
+	count := m.Where(sql, adminId).MustCount()
+	sessions := m.NewSlice()
+	m.Find().Where(sql, adminId).OrderBy(q.OrderByValue()).Limit(q.Limit()).Offset(q.Offset()).MustQuery(sessions.Interface())
+
+	ret := struct {
+		Sessions   []interface{}
+		Current    int
+		Pagination interface{}
+	}{
+		Sessions:   []interface{}{},
+		Pagination: q.PaginationQuerySortResult(count),
+	}
+	if id, sessionId, ok := ctrl.backend.FiberGetAdminAndSessionId(c); ok && id == adminId {
+		m.Select(m.ToColumnName("Id")).WHERE(getName(c, "AdminId"), "=", id, getName(c, "SessionId"), "=", sessionId).QueryRow(&ret.Current)
+	}
+	for i := 0; i < sessions.Elem().Len(); i++ {
+		ret.Sessions = append(ret.Sessions, serialize(sessions.Elem().Index(i).Addr().Interface(), "list"))
+	}
+	return c.JSON(ret)
+}
+
+// RevokeSession deletes one session of the admin. The admin signed in with
+// the session is signed out.
+func (ctrl fiberAdminsCtrl) RevokeSession(c FiberCtx) error {
+	adminId, err := ctrl.adminId(c)
+	if err != nil {
+		return err
+	}
+	m := ctrl.backend.ModelByName(getName(c, "AdminSession"))
+	var id int
+	m.Select(m.ToColumnName("Id")).
+		WHERE(getName(c, "AdminId"), "=", adminId, "Id", "=", c.Params("sessionId")).MustQueryRow(&id)
+	m.Delete().WHERE("Id", "=", id).MustExecute()
+	return c.SendStatus(204)
+}
+
+// RevokeSessions deletes all sessions of the admin.
+func (ctrl fiberAdminsCtrl) RevokeSessions(c FiberCtx) error {
+	adminId, err := ctrl.adminId(c)
+	if err != nil {
+		return err
+	}
+	ctrl.backend.ModelByName(getName(c, "AdminSession")).
+		Delete().WHERE(getName(c, "AdminId"), "=", adminId).MustExecute()
+	return c.SendStatus(204)
+}
+
+// adminId returns ID of the admin of the id param in the tenant of the
+// request.
+func (ctrl fiberAdminsCtrl) adminId(c FiberCtx) (id int, err error) {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	conds, err := ctrl.backend.FiberTenantConditions(c, m)
+	if err != nil {
+		return
+	}
+	conds.Add(fmt.Sprintf("%s = $?", m.ToColumnName("Id")), c.Params("id"))
+	err = m.Select(m.ToColumnName("Id")).Where(conds.String(), conds.Args()...).QueryRow(&id)
+	return
+}
+
 func (ctrl fiberAdminsCtrl) params(c FiberCtx, action string) []string {
-	admin := ctrl.backend.ModelByName(getName(c, "Admin")).New().Interface()
+	return adminParams(ctrl.backend.ModelByName(getName(c, "Admin")).New().Interface(), action)
+}
+
+// adminParams returns permitted params of admin for action.
+func adminParams(admin interface{}, action string) []string {
 	if admin, ok := admin.(HasParams); ok {
 		return admin.Params(action)
 	}
@@ -0,0 +1,601 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NewFiberGraphQLCtrl creates a controller for fiber executing GraphQL
+// queries and mutations over the registered models. Handlers maps names of
+// the routes of the controllers, like "Post.List" or "Admin.Update", to
+// their handlers, only the List, Show, Create, Update and Destroy actions in
+// handlers are exposed:
+//
+//	query {
+//	  posts(page: 1, per: 10, sort: "id", order: "desc", filter: {title: {like: "%go%"}}) {
+//	    Records { Id Title }
+//	    Pagination { Count }
+//	  }
+//	  post(id: 1) { Id Title Comments { Id } }
+//	}
+//	mutation {
+//	  createPost(input: {Title: "Hello"}) { Id }
+//	  updatePost(id: 1, input: {Title: "World"}) { Id Title }
+//	  destroyPost(id: 1) { Id }
+//	}
+//
+// Fields are resolved by the handlers, so pagination, sorting, filters,
+// permitted params, validations and hooks work the same as the REST
+// endpoints. Selected relations are included like the include query. Errors
+// of fields are added to "errors" with the status and the errors of
+// HandleError() in "extensions". The schema of the fields can be queried
+// with __schema and __type introspection.
+func (backend *Backend) NewFiberGraphQLCtrl(handlers map[string]FiberHandler) *fiberGraphQLCtrl {
+	return &fiberGraphQLCtrl{
+		backend:  backend,
+		handlers: handlers,
+	}
+}
+
+type (
+	fiberGraphQLCtrl struct {
+		backend  *Backend
+		handlers map[string]FiberHandler
+	}
+
+	// graphQLCtx is FiberCtx of handlers resolving GraphQL fields, taking
+	// params, queries and body from the arguments and capturing the JSON
+	// response.
+	graphQLCtx struct {
+		FiberCtx
+		method  string
+		id      string
+		query   url.Values
+		body    []byte
+		status  int
+		out     interface{}
+		keyCase KeyCase
+	}
+
+	graphQLError struct {
+		Message    string                 `json:"message"`
+		Path       []string               `json:"path,omitempty"`
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}
+)
+
+// Execute executes the GraphQL request of POST body {"query": "...",
+// "variables": {...}, "operationName": "..."} or GET queries of the same
+// names.
+func (ctrl fiberGraphQLCtrl) Execute(c FiberCtx) error {
+	if ctrl.backend.FiberGetCurrentAdmin(c) == nil {
+		c.SendStatus(401)
+		return c.JSON(struct {
+			Message string
+		}{"Unauthorized"})
+	}
+	var req struct {
+		Query         string
+		Variables     map[string]interface{}
+		OperationName string
+	}
+	if c.Method() == "GET" {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return InputErrors{NewInputError("variables", "invalid")}
+			}
+		}
+	} else if err := ctrl.parseBody(c, &req); err != nil {
+		return InputErrors{NewInputError("body", "invalid")}
+	}
+	return c.JSON(keepKeys{ctrl.execute(c, req.Query, req.Variables, req.OperationName)})
+}
+
+// parseBody parses POST body of the request into req. Names of the
+// properties are matched in the key case, keys of the variables are kept.
+func (ctrl fiberGraphQLCtrl) parseBody(c FiberCtx, req interface{}) error {
+	body := c.Body()
+	if r, ok := c.(interface{ rawBody() []byte }); ok {
+		body = r.rawBody()
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return err
+	}
+	props := map[string]json.RawMessage{}
+	for key, value := range obj {
+		props[ctrl.backend.keyCase.toGo(key)] = value
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, req)
+}
+
+// execute returns the GraphQL response of the operation.
+func (ctrl fiberGraphQLCtrl) execute(c FiberCtx, query string, variables map[string]interface{}, operationName string) interface{} {
+	type response struct {
+		Data   interface{}    `json:"data"`
+		Errors []graphQLError `json:"errors,omitempty"`
+	}
+	fail := func(message string) interface{} {
+		return struct {
+			Errors []graphQLError `json:"errors"`
+		}{[]graphQLError{{Message: message}}}
+	}
+	operations, err := parseGraphQL(query)
+	if err != nil {
+		return fail(err.Error())
+	}
+	var op *gqlOperation
+	for i := range operations {
+		if operationName == "" && len(operations) > 1 {
+			return fail("Must provide operation name if query contains multiple operations.")
+		}
+		if operationName == "" || operations[i].name == operationName {
+			op = &operations[i]
+			break
+		}
+	}
+	if op == nil {
+		return fail(fmt.Sprintf("Unknown operation named %q.", operationName))
+	}
+	if op.typ == "mutation" && c.Method() == "GET" {
+		return fail("Can only perform a mutation operation from a POST request.")
+	}
+	vars := map[string]interface{}{}
+	for name, value := range op.variables {
+		vars[name] = value
+	}
+	for name, value := range variables {
+		vars[name] = value
+	}
+
+	var ret response
+	var data jsonObject
+	for _, field := range op.selections {
+		value, err := ctrl.resolve(c, op.typ, field, vars)
+		if err != nil {
+			ret.Errors = append(ret.Errors, ctrl.error(err, field.key()))
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fail(err.Error())
+		}
+		data.keys = append(data.keys, field.key())
+		data.values = append(data.values, b)
+	}
+	ret.Data = data
+	return ret
+}
+
+// resolve resolves root field of the operation with the handler of its
+// action.
+func (ctrl fiberGraphQLCtrl) resolve(c FiberCtx, typ string, field gqlField, vars map[string]interface{}) (interface{}, error) {
+	if field.name == "__typename" {
+		return upperFirst(typ), nil
+	}
+	if typ == "query" && (field.name == "__schema" || field.name == "__type") {
+		return ctrl.introspect(field, vars)
+	}
+	model, action := ctrl.action(typ, field.name)
+	handler := ctrl.handlers[model+"."+action]
+	if handler == nil {
+		return nil, graphQLFieldError(fmt.Sprintf("Cannot query field %q on type %q.", field.name, upperFirst(typ)))
+	}
+	args := map[string]interface{}{}
+	for name, value := range field.arguments {
+		args[name] = resolveVariables(value, vars)
+	}
+	gc := &graphQLCtx{FiberCtx: c, query: url.Values{}, keyCase: ctrl.backend.keyCase}
+	if err := gc.setArguments(action, args); err != nil {
+		return nil, err
+	}
+
+	selections := field.selections
+	if action == "List" {
+		selections = nil
+		for _, s := range field.selections {
+			selections = append(selections, s.selections...)
+		}
+	}
+	var relations map[string]Relation
+	if m := ctrl.backend.ModelByName(model); m != nil {
+		if r, ok := m.New().Interface().(HasRelations); ok {
+			relations = r.Relations()
+		}
+	}
+	var includes []string
+	for _, s := range selections {
+		if name := findRelation(relations, ctrl.backend.keyCase.toGo(s.name)); name != "" {
+			includes = append(includes, name)
+		}
+	}
+	if len(includes) > 0 {
+		gc.query.Set("include", strings.Join(includes, ","))
+	}
+
+	if err := gc.run(handler); err != nil {
+		return nil, err
+	}
+	if gc.status >= 400 {
+		return nil, graphQLStatusError{gc.status, gc.out}
+	}
+	value, err := graphQLValue(gc.out)
+	if err != nil {
+		return nil, err
+	}
+	if obj, ok := value.(map[string]interface{}); ok && action == "List" {
+		if _, ok := obj["Records"]; !ok {
+			for _, v := range obj {
+				if _, ok := v.([]interface{}); ok {
+					obj["Records"] = v
+					break
+				}
+			}
+		}
+	}
+	typeName := model
+	if action == "List" {
+		typeName = model + "List"
+	}
+	return ctrl.selectFields(value, field.selections, typeName, model, relations)
+}
+
+// action returns model and action of the root field name: list field is the
+// plural of the model name (posts), show field is the model name (post),
+// mutations are the action followed by the model name (createPost).
+func (ctrl fiberGraphQLCtrl) action(typ, name string) (model, action string) {
+	var models []string
+	for key := range ctrl.handlers {
+		models = append(models, strings.SplitN(key, ".", 2)[0])
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		if typ == "query" {
+			switch name {
+			case lowerFirst(pluralize(model)):
+				return model, "List"
+			case lowerFirst(model):
+				return model, "Show"
+			}
+			continue
+		}
+		for _, action := range []string{"Create", "Update", "Destroy"} {
+			if name == strings.ToLower(action)+model {
+				return model, action
+			}
+		}
+	}
+	return "", ""
+}
+
+// selectFields returns value with the selected fields in order. Fields are
+// matched case-insensitively and in the key case, keys of values without
+// selections are converted to the key case.
+func (ctrl fiberGraphQLCtrl) selectFields(value interface{}, selections []gqlField, typeName, model string, relations map[string]Relation) (interface{}, error) {
+	if len(selections) == 0 || value == nil {
+		if ctrl.backend.keyCase != KeyCaseGo {
+			return casedJSON{value, ctrl.backend.keyCase}, nil
+		}
+		return value, nil
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			var err error
+			if out[i], err = ctrl.selectFields(v[i], selections, typeName, model, relations); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case map[string]interface{}:
+		var keys []string
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var obj jsonObject
+		for _, s := range selections {
+			if len(s.arguments) > 0 {
+				return nil, graphQLFieldError(fmt.Sprintf("Unknown arguments on field %q of type %q.", s.name, typeName))
+			}
+			var fieldValue interface{}
+			if s.name == "__typename" {
+				fieldValue = typeName
+			} else {
+				key := findParam(keys, ctrl.backend.keyCase.toGo(s.name))
+				if key == "" {
+					return nil, graphQLFieldError(fmt.Sprintf("Cannot query field %q on type %q.", s.name, typeName))
+				}
+				childType, childRelations := "JSON", map[string]Relation(nil)
+				switch {
+				case typeName == model+"List" && strings.EqualFold(key, "Records"):
+					childType, childRelations = model, relations
+				case typeName == model+"List" && strings.EqualFold(key, "Pagination"):
+					childType = "Pagination"
+				case findRelation(relations, key) != "":
+					childType = relations[findRelation(relations, key)].Model
+				}
+				var err error
+				fieldValue, err = ctrl.selectFields(v[key], s.selections, childType, model, childRelations)
+				if err != nil {
+					return nil, err
+				}
+			}
+			b, err := json.Marshal(fieldValue)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, s.key())
+			obj.values = append(obj.values, b)
+		}
+		return obj, nil
+	}
+	return nil, graphQLFieldError(fmt.Sprintf("Field of type %q must not have a selection.", typeName))
+}
+
+// error converts err of root field to GraphQL error.
+func (ctrl fiberGraphQLCtrl) error(err error, path string) graphQLError {
+	status, content := 0, interface{}(nil)
+	if e, ok := err.(graphQLStatusError); ok {
+		status, content = e.status, e.content
+	} else if _, ok := err.(graphQLFieldError); ok {
+		return graphQLError{Message: err.Error(), Path: []string{path}}
+	} else {
+		status, content = ctrl.backend.HandleError(err)
+	}
+	kc := ctrl.backend.keyCase
+	gerr := graphQLError{
+		Message:    http.StatusText(status),
+		Path:       []string{path},
+		Extensions: map[string]interface{}{kc.fromGo("Status"): status},
+	}
+	b, _ := json.Marshal(casedJSON{content, kc})
+	var obj map[string]interface{}
+	if json.Unmarshal(b, &obj) == nil && (obj[kc.fromGo("Errors")] != nil || obj[kc.fromGo("Message")] != nil) {
+		for key, value := range obj {
+			if key == kc.fromGo("Message") {
+				gerr.Message, _ = value.(string)
+				continue
+			}
+			gerr.Extensions[key] = value
+		}
+	} else if content != nil {
+		gerr.Extensions[kc.fromGo("Current")] = casedJSON{content, kc}
+	}
+	return gerr
+}
+
+type (
+	// graphQLStatusError is error response of handler.
+	graphQLStatusError struct {
+		status  int
+		content interface{}
+	}
+
+	// graphQLFieldError is error of query like unknown field or argument.
+	graphQLFieldError string
+)
+
+func (err graphQLStatusError) Error() string {
+	return http.StatusText(err.status)
+}
+
+func (err graphQLFieldError) Error() string {
+	return string(err)
+}
+
+// setArguments sets params, queries and body of the action from the
+// arguments of the field.
+func (c *graphQLCtx) setArguments(action string, args map[string]interface{}) error {
+	allowed := map[string][]string{
+		"List":    {"page", "per", "sort", "order", "query", "status", "filter", "pagination", "after", "before"},
+		"Show":    {"id"},
+		"Create":  {"input"},
+		"Update":  {"id", "input"},
+		"Destroy": {"id"},
+	}[action]
+	c.method = map[string]string{"Create": "POST", "Update": "PATCH", "Destroy": "DELETE"}[action]
+	if c.method == "" {
+		c.method = "GET"
+	}
+	var names []string
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for _, a := range allowed {
+			found = found || a == name
+		}
+		if !found {
+			return graphQLFieldError(fmt.Sprintf("Unknown argument %q.", name))
+		}
+		switch value := args[name]; name {
+		case "id":
+			c.id = graphQLString(value)
+		case "input":
+			if _, ok := value.(map[string]interface{}); !ok {
+				return graphQLFieldError("Argument \"input\" must be an object.")
+			}
+			b, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if c.keyCase != KeyCaseGo {
+				if b, err = convertJSONKeys(b, c.keyCase.toGo, 0); err != nil {
+					return err
+				}
+			}
+			c.body = b
+		case "filter":
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return graphQLFieldError("Argument \"filter\" must be an object.")
+			}
+			for field, v := range obj {
+				if ops, ok := v.(map[string]interface{}); ok {
+					for op, v := range ops {
+						c.query.Set(c.keyCase.convertQuery("filter["+field+"]["+op+"]", graphQLString(v)))
+					}
+				} else {
+					c.query.Set(c.keyCase.convertQuery("filter["+field+"]", graphQLString(v)))
+				}
+			}
+		default:
+			c.query.Set(c.keyCase.convertQuery(name, graphQLString(value)))
+		}
+	}
+	return nil
+}
+
+// run runs handler, recovering panics of errors like ErrNoRows of MustQuery.
+func (c *graphQLCtx) run(handler FiberHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	return handler(c)
+}
+
+func (c *graphQLCtx) Body() []byte {
+	return c.body
+}
+
+func (c *graphQLCtx) BodyParser(out interface{}) error {
+	return json.Unmarshal(c.body, out)
+}
+
+// Get returns headers of the GraphQL request, except the ones describing
+// the body and the conditional requests of the REST endpoints.
+func (c *graphQLCtx) Get(key string, defaultValue ...string) string {
+	switch strings.ToLower(key) {
+	case "content-length":
+		return strconv.Itoa(len(c.body))
+	case "content-type":
+		return "application/json"
+	case "if-match", "if-none-match":
+		return valueOrDefault("", defaultValue)
+	}
+	return c.FiberCtx.Get(key, defaultValue...)
+}
+
+func (c *graphQLCtx) JSON(data interface{}, ctype ...string) error {
+	c.out = data
+	return nil
+}
+
+func (c *graphQLCtx) Method(override ...string) string {
+	return c.method
+}
+
+func (c *graphQLCtx) Next() error {
+	return nil
+}
+
+func (c *graphQLCtx) Params(key string, defaultValue ...string) string {
+	if key == "id" {
+		return valueOrDefault(c.id, defaultValue)
+	}
+	return valueOrDefault("", defaultValue)
+}
+
+func (c *graphQLCtx) Queries() map[string]string {
+	queries := map[string]string{}
+	for key := range c.query {
+		queries[key] = c.query.Get(key)
+	}
+	return queries
+}
+
+func (c *graphQLCtx) Query(key string, defaultValue ...string) string {
+	return valueOrDefault(c.query.Get(key), defaultValue)
+}
+
+func (c *graphQLCtx) QueryParser(out interface{}) error {
+	return decodeValues(out, c.query, "query")
+}
+
+func (c *graphQLCtx) SendStatus(status int) error {
+	c.status = status
+	return nil
+}
+
+func (c *graphQLCtx) SendStream(stream io.Reader, size ...int) error {
+	return errors.New("streams are not supported in GraphQL")
+}
+
+func (c *graphQLCtx) Set(key string, val string) {}
+
+// graphQLValue converts output of handler to JSON values, keeping numbers as
+// json.Number.
+func graphQLValue(out interface{}) (value interface{}, err error) {
+	b, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err = dec.Decode(&value)
+	return
+}
+
+// graphQLString converts argument value to string of query or param. Lists
+// are joined with commas.
+func graphQLString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i := range v {
+			items[i] = graphQLString(v[i])
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// pluralize returns plural form of English noun name.
+func pluralize(name string) string {
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(name, suffix) {
+			return name + "es"
+		}
+	}
+	if n := len(name); n > 1 && name[n-1] == 'y' && !strings.ContainsRune("aeiouAEIOU", rune(name[n-2])) {
+		return name[:n-1] + "ies"
+	}
+	return name + "s"
+}
+
+// lowerFirst returns s with the first letter in lower case.
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
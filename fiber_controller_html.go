@@ -0,0 +1,528 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopsql/psql"
+)
+
+//go:embed html
+var htmlFiles embed.FS
+
+var htmlTemplates = template.Must(template.ParseFS(htmlFiles, "html/*.html"))
+
+// Name of the cookie containing the session token of the HTML controllers.
+const htmlSessionCookie = "session"
+
+// Name of the form field containing the CSRF token, see htmlCSRFToken().
+const htmlCSRFField = "CSRFToken"
+
+type (
+	// htmlPage is the data of the HTML templates.
+	htmlPage struct {
+		Path         string // path of the HTML routes
+		Title        string
+		CurrentAdmin string
+		CSRFToken    string
+		Message      string
+		Fields       []htmlField
+
+		// admin form
+		Action     string
+		DestroyURL string
+
+		// admins table
+		Query      string
+		Sort       string
+		Order      string
+		Columns    []htmlColumn
+		Rows       []htmlRow
+		Page       int
+		TotalPages int
+		PrevURL    string
+		NextURL    string
+	}
+
+	htmlField struct {
+		Name     string
+		Type     string
+		Value    string
+		Error    string
+		Required bool
+	}
+
+	htmlColumn struct {
+		Name  string
+		URL   string // empty if not sortable
+		Order string // order if the table is sorted by the column
+	}
+
+	htmlRow struct {
+		URL    string
+		Values []string
+	}
+)
+
+// NewFiberHTMLSessionsCtrl creates sessions controller for fiber rendering
+// HTML pages. Path is the path of the HTML routes, see
+// NewFiberHTMLAdminsCtrl(). The session token is kept in a cookie.
+func (backend *Backend) NewFiberHTMLSessionsCtrl(path string) *fiberHTMLSessionsCtrl {
+	return &fiberHTMLSessionsCtrl{
+		backend: backend,
+		path:    path,
+	}
+}
+
+type fiberHTMLSessionsCtrl struct {
+	backend *Backend
+	path    string
+}
+
+// Authenticate redirects to the sign-in page if the session cookie does not
+// belong to any admin. POST requests without the CSRF token of the session
+// in the form are forbidden.
+func (ctrl fiberHTMLSessionsCtrl) Authenticate(c FiberCtx) error {
+	if fiberHTMLCurrentAdmin(ctrl.backend, c) == nil {
+		return fiberRedirect(c, ctrl.path+"/sign-in")
+	}
+	if c.Method() == "POST" {
+		form, _ := url.ParseQuery(string(c.Body()))
+		if !hmac.Equal([]byte(form.Get(htmlCSRFField)), []byte(htmlCSRFToken(c))) {
+			return c.SendStatus(403)
+		}
+	}
+	return c.Next()
+}
+
+func (ctrl fiberHTMLSessionsCtrl) SignInForm(c FiberCtx) error {
+	return renderHTML(c, 200, "sign_in", ctrl.signInPage(c, nil))
+}
+
+func (ctrl fiberHTMLSessionsCtrl) SignIn(c FiberCtx) error {
+	token, err := ctrl.backend.FiberValidateNewSession(c)
+	if err != nil {
+		messages, ok := htmlErrorMessages(ctrl.backend, err)
+		if !ok {
+			return err
+		}
+		return renderHTML(c, 400, "sign_in", ctrl.signInPage(c, messages))
+	}
+	ctrl.setCookie(c, token, 0)
+	return fiberRedirect(c, ctrl.path+"/admins")
+}
+
+func (ctrl fiberHTMLSessionsCtrl) SignOut(c FiberCtx) error {
+	if fiberHTMLCurrentAdmin(ctrl.backend, c) != nil {
+		if err := ctrl.backend.FiberDeleteSession(c); err != nil {
+			return err
+		}
+	}
+	ctrl.setCookie(c, "", -1)
+	return fiberRedirect(c, ctrl.path+"/sign-in")
+}
+
+func (ctrl fiberHTMLSessionsCtrl) signInPage(c FiberCtx, messages map[string]string) htmlPage {
+	form, _ := url.ParseQuery(string(c.Body()))
+	return htmlPage{
+		Path:    ctrl.path,
+		Title:   "Sign in",
+		Message: messages[""],
+		Fields: []htmlField{
+			{Name: "Name", Type: "text", Value: form.Get("Name"), Error: messages["Name"], Required: true},
+			{Name: "Password", Type: "password", Error: messages["Password"], Required: true},
+		},
+	}
+}
+
+// setCookie sets the session cookie, which is secure if the request is
+// made over HTTPS.
+func (ctrl fiberHTMLSessionsCtrl) setCookie(c FiberCtx, token string, maxAge int) {
+	path := ctrl.path
+	if path == "" {
+		path = "/"
+	}
+	c.Set("Set-Cookie", (&http.Cookie{
+		Name:     htmlSessionCookie,
+		Value:    token,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Protocol() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}).String())
+}
+
+// NewFiberHTMLAdminsCtrl creates admins controller for fiber rendering HTML
+// pages with html/template: a table of admins with pagination, search and
+// sort, and forms of the permitted params with validation errors shown next
+// to the fields. Forms are submitted as application/x-www-form-urlencoded.
+// Path is the path of the HTML routes, the admin pages are at path+"/admins".
+func (backend *Backend) NewFiberHTMLAdminsCtrl(path string) *fiberHTMLAdminsCtrl {
+	return &fiberHTMLAdminsCtrl{
+		backend: backend,
+		path:    path,
+		admins:  fiberAdminsCtrl{backend},
+	}
+}
+
+type fiberHTMLAdminsCtrl struct {
+	backend *Backend
+	path    string
+	admins  fiberAdminsCtrl
+}
+
+func (ctrl fiberHTMLAdminsCtrl) List(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	q, sort := ctrl.admins.listQuery(c, m)
+	conds, rank, err := ctrl.admins.listConditions(c, m, q.GetLikePattern())
+	if err != nil {
+		return err
+	}
+	sql := conds.String()
+	count := m.Where(sql, conds.Args()...).MustCount()
+	admins := m.NewSlice()
+	m.Find().Where(sql, conds.Args()...).OrderBy(orderByRank(c, q, rank)).Limit(q.Limit()).Offset(q.Offset()).MustQuery(admins.Interface())
+
+	page := ctrl.page(c, "Admins")
+	page.Query = c.Query("query")
+	page.Sort = c.Query("sort", sort.DefaultSort)
+	page.Order = strings.ToLower(c.Query("order", sort.DefaultOrder))
+	listURL := func(changes ...string) string {
+		values := url.Values{}
+		for key, value := range c.Queries() {
+			values.Set(key, value)
+		}
+		for i := 0; i+1 < len(changes); i += 2 {
+			values.Set(changes[i], changes[i+1])
+		}
+		return ctrl.path + "/admins?" + values.Encode()
+	}
+
+	fields := htmlColumnFields(m)
+	for _, field := range fields {
+		column := htmlColumn{Name: field}
+		if key := m.ToColumnName(field); sort.AllowedSorts[key] != "" {
+			order := "asc"
+			if page.Sort == key {
+				column.Order = page.Order
+				if page.Order == "asc" {
+					order = "desc"
+				}
+			}
+			column.URL = listURL("sort", key, "order", order, "page", "1")
+		}
+		page.Columns = append(page.Columns, column)
+	}
+	for i := 0; i < admins.Elem().Len(); i++ {
+		rv := admins.Elem().Index(i)
+		row := htmlRow{URL: fmt.Sprintf("%s/admins/%v", ctrl.path, rv.FieldByName("Id").Interface())}
+		for _, field := range fields {
+			row.Values = append(row.Values, htmlValue(rv.FieldByName(field)))
+		}
+		page.Rows = append(page.Rows, row)
+	}
+
+	page.Page = q.Offset()/q.Limit() + 1
+	page.TotalPages = (count + q.Limit() - 1) / q.Limit()
+	if page.TotalPages < 1 {
+		page.TotalPages = 1
+	}
+	if page.Page > 1 {
+		page.PrevURL = listURL("page", strconv.Itoa(page.Page-1))
+	}
+	if page.Page < page.TotalPages {
+		page.NextURL = listURL("page", strconv.Itoa(page.Page+1))
+	}
+	return renderHTML(c, 200, "admins", page)
+}
+
+func (ctrl fiberHTMLAdminsCtrl) New(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	return ctrl.renderForm(c, m, m.New().Interface(), "create", nil, nil)
+}
+
+func (ctrl fiberHTMLAdminsCtrl) Create(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	form, _ := url.ParseQuery(string(c.Body()))
+	params := ctrl.admins.params(c, "create")
+	body, err := formToJSON(admin, params, form)
+	if err == nil {
+		changes := m.MustAssign(admin, m.Permit(params...).Filter(body), m.CreatedAt(), m.UpdatedAt())
+		err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+			if err := ctrl.backend.CreateInTransaction(tx, m, admin, changes); err != nil {
+				return err
+			}
+			return ctrl.backend.fiberAddTenantMemberInTransaction(c, tx, m, admin)
+		})
+	}
+	if err == nil {
+		return fiberRedirect(c, ctrl.path+"/admins")
+	}
+	return ctrl.renderForm(c, m, admin, "create", form, err)
+}
+
+func (ctrl fiberHTMLAdminsCtrl) Edit(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), admin); err != nil {
+		return err
+	}
+	return ctrl.renderForm(c, m, admin, "update", nil, nil)
+}
+
+// Update saves the submitted fields. Write-only fields left blank, like
+// password, are not changed.
+func (ctrl fiberHTMLAdminsCtrl) Update(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	current := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), current); err != nil {
+		return err
+	}
+	form, _ := url.ParseQuery(string(c.Body()))
+	for _, field := range writeOnlyFields(current) {
+		if form.Get(field) == "" {
+			delete(form, field)
+		}
+	}
+	params := ctrl.admins.params(c, "update")
+	admin := current
+	body, err := formToJSON(current, params, form)
+	if err == nil {
+		var changes []interface{}
+		admin, changes, err = ctrl.backend.AssignUpdate(m, current, body, params, false)
+		if err == nil {
+			err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+				return ctrl.backend.UpdateInTransaction(tx, m, current, admin, changes)
+			})
+		}
+	}
+	if err == nil {
+		return fiberRedirect(c, ctrl.path+"/admins")
+	}
+	return ctrl.renderForm(c, m, admin, "update", form, err)
+}
+
+// Destroy soft deletes the admin and deletes its sessions.
+func (ctrl fiberHTMLAdminsCtrl) Destroy(c FiberCtx) error {
+	m := ctrl.backend.ModelByName(getName(c, "Admin"))
+	admin := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), admin); err != nil {
+		return err
+	}
+	mSessions := ctrl.backend.ModelByName(getName(c, "AdminSession"))
+	err := ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		if err := ctrl.backend.DestroyInTransaction(tx, m, admin, false); err != nil {
+			return err
+		}
+		return mSessions.Delete().WHERE(getName(c, "AdminId"), "=", c.Params("id")).ExecuteInTransaction(tx)
+	})
+	if err != nil {
+		return ctrl.renderForm(c, m, admin, "update", nil, err)
+	}
+	return fiberRedirect(c, ctrl.path+"/admins")
+}
+
+func (ctrl fiberHTMLAdminsCtrl) page(c FiberCtx, title string) htmlPage {
+	page := htmlPage{Path: ctrl.path, Title: title}
+	if admin, ok := fiberHTMLCurrentAdmin(ctrl.backend, c).(IsAdmin); ok {
+		page.CurrentAdmin = admin.GetName()
+		page.CSRFToken = htmlCSRFToken(c)
+	}
+	return page
+}
+
+// renderForm renders the form of the permitted params of action. Submitted
+// form values are kept and messages of input errors are shown next to the
+// fields. Errors other than input errors are returned.
+func (ctrl fiberHTMLAdminsCtrl) renderForm(c FiberCtx, m *psql.Model, admin interface{}, action string, form url.Values, err error) error {
+	var messages map[string]string
+	status := 200
+	if err != nil {
+		var ok bool
+		if messages, ok = htmlErrorMessages(ctrl.backend, err); !ok {
+			return err
+		}
+		status = 400
+	}
+	rv := reflect.ValueOf(admin).Elem()
+	id := rv.FieldByName("Id").Interface()
+	page := ctrl.page(c, "New admin")
+	page.Action = ctrl.path + "/admins"
+	if action == "update" {
+		page.Title = fmt.Sprintf("Admin %v", id)
+		page.Action = fmt.Sprintf("%s/admins/%v", ctrl.path, id)
+		page.DestroyURL = page.Action + "/destroy"
+	}
+	writeOnly := writeOnlyFields(admin)
+	for _, param := range ctrl.admins.params(c, action) {
+		f, ok := rv.Type().FieldByName(param)
+		if !ok {
+			continue
+		}
+		field := htmlField{
+			Name:     param,
+			Type:     htmlInputType(f.Type),
+			Value:    htmlValue(rv.FieldByName(param)),
+			Error:    messages[param],
+			Required: action == "create" && strings.Contains(","+f.Tag.Get("validate")+",", ",required,"),
+		}
+		if values, ok := form[param]; ok && len(values) > 0 {
+			field.Value = values[len(values)-1]
+		}
+		if findParam(writeOnly, param) != "" {
+			field.Type, field.Value = "password", ""
+		}
+		page.Fields = append(page.Fields, field)
+		delete(messages, param)
+	}
+	page.Message = htmlJoinMessages(messages)
+	return renderHTML(c, status, "admin", page)
+}
+
+// fiberHTMLCurrentAdmin is like FiberGetCurrentAdmin() but the session token
+// is read from the session cookie.
+func fiberHTMLCurrentAdmin(backend *Backend, c FiberCtx) interface{} {
+	if c.Locals("Authorization") == nil {
+		req := http.Request{Header: http.Header{"Cookie": {c.Get("Cookie")}}}
+		if cookie, err := req.Cookie(htmlSessionCookie); err == nil {
+			c.Locals("Authorization", cookie.Value)
+		} else {
+			c.Locals("Authorization", "")
+		}
+	}
+	return backend.FiberGetCurrentAdmin(c)
+}
+
+// htmlCSRFToken returns the CSRF token of the session of the request, HMAC
+// of the session token, so it differs in each session and cannot be made
+// without the session cookie.
+func htmlCSRFToken(c FiberCtx) string {
+	token, _ := c.Locals("Authorization").(string)
+	if token == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(htmlCSRFField))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func renderHTML(c FiberCtx, status int, name string, page htmlPage) error {
+	var buf bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&buf, name, page); err != nil {
+		return err
+	}
+	c.SendStatus(status)
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.SendStream(&buf, buf.Len())
+}
+
+func fiberRedirect(c FiberCtx, location string) error {
+	c.Set("Location", location)
+	return c.SendStatus(303)
+}
+
+// htmlErrorMessages returns messages of input errors by field name, false
+// if err is not input error.
+func htmlErrorMessages(backend *Backend, err error) (map[string]string, bool) {
+	ierrs, ok := backend.inputErrorsWithIndex(err, 0)
+	if !ok {
+		return nil, false
+	}
+	messages := map[string]string{}
+	for _, e := range ierrs {
+		message := htmlErrorMessage(e.InputError)
+		if messages[e.Name] != "" {
+			message = messages[e.Name] + ", " + message
+		}
+		messages[e.Name] = message
+	}
+	return messages, true
+}
+
+func htmlErrorMessage(e InputError) string {
+	subject := "must be"
+	if e.Kind == "string" && e.Param != "" {
+		subject = "length must be"
+	}
+	switch e.Type {
+	case "required":
+		return "is required"
+	case "gt":
+		return fmt.Sprintf("%s greater than %s", subject, e.Param)
+	case "gte", "min":
+		return fmt.Sprintf("%s at least %s", subject, e.Param)
+	case "lt":
+		return fmt.Sprintf("%s less than %s", subject, e.Param)
+	case "lte", "max":
+		return fmt.Sprintf("%s at most %s", subject, e.Param)
+	case "uniqueness":
+		return "has already been taken"
+	case "wrong", "invalid", "deleted", "not_found":
+		return "is " + strings.Replace(e.Type, "_", " ", -1)
+	}
+	return strings.TrimSpace(e.Type + " " + e.Param)
+}
+
+func htmlJoinMessages(messages map[string]string) string {
+	var parts []string
+	for name, message := range messages {
+		parts = append(parts, strings.TrimSpace(name+" "+message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// htmlColumnFields returns names of the fields of model m shown in tables.
+func htmlColumnFields(m *psql.Model) (fields []string) {
+	record := m.New().Interface()
+	writeOnly := writeOnlyFields(record)
+	typ := reflect.TypeOf(record).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || f.Anonymous || f.Name == "DeletedAt" || findParam(writeOnly, f.Name) != "" {
+			continue
+		}
+		fields = append(fields, f.Name)
+	}
+	return
+}
+
+func htmlInputType(typ reflect.Type) string {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "checkbox"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "text"
+}
+
+func htmlValue(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,296 @@
+package backend
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/gopsql/pagination/v2"
+	"github.com/gopsql/psql"
+)
+
+// NewFiberModelsCtrl creates a simple controller for fiber of the model
+// registered with given type name, see ModelByName(). Records can be sorted
+// by ID and the fields returned by Filters() if the model is Filterable. If
+// the model has DeletedAt field, records are soft deleted and can be
+// restored. If the model has TenantId field, records are limited to the
+// tenant of the request, see FiberTenantConditions().
+func (backend *Backend) NewFiberModelsCtrl(name string) *fiberModelsCtrl {
+	return &fiberModelsCtrl{
+		backend: backend,
+		name:    name,
+	}
+}
+
+type fiberModelsCtrl struct {
+	backend *Backend
+	name    string
+}
+
+func (ctrl fiberModelsCtrl) model() *psql.Model {
+	return ctrl.backend.ModelByName(ctrl.name)
+}
+
+func (ctrl fiberModelsCtrl) softDelete(m *psql.Model) bool {
+	_, ok := reflect.TypeOf(m.New().Interface()).Elem().FieldByName("DeletedAt")
+	return ok
+}
+
+func (ctrl fiberModelsCtrl) List(c FiberCtx) error {
+	m := ctrl.model()
+	q, sort := ctrl.listQuery(c, m)
+	conds, rank, err := ctrl.listConditions(c, m)
+	if err != nil {
+		return err
+	}
+
+	ret := struct {
+		Records    []interface{}
+		Pagination interface{}
+	}{}
+
+	records := m.NewSlice()
+	if c.Query("pagination") == "cursor" {
+		p := NewFiberCursorPagination(c, sort, q.Limit())
+		result, err := ctrl.backend.FindWithCursor(m, conds, p, records)
+		if err != nil {
+			return err
+		}
+		ret.Pagination = result
+	} else {
+		sql := conds.String()
+		count := m.Where(sql, conds.Args()...).MustCount()
+		m.Find().Where(sql, conds.Args()...).OrderBy(orderByRank(c, q, rank)).Limit(q.Limit()).Offset(q.Offset()).MustQuery(records.Interface())
+		ret.Pagination = q.PaginationQuerySortResult(count)
+	}
+
+	var elems []interface{}
+	for i := 0; i < records.Elem().Len(); i++ {
+		elems = append(elems, records.Elem().Index(i).Addr().Interface())
+	}
+	if ret.Records, err = ctrl.backend.FiberSerialize(c, m, "list", elems...); err != nil {
+		return err
+	}
+	return c.JSON(ret)
+}
+
+// Export streams all records matching the status and filters of the list
+// endpoint as CSV, XLSX or NDJSON file according to the format param or
+// query.
+func (ctrl fiberModelsCtrl) Export(c FiberCtx) error {
+	m := ctrl.model()
+	q, sort := ctrl.listQuery(c, m)
+	conds, _, err := ctrl.listConditions(c, m)
+	if err != nil {
+		return err
+	}
+	format := c.Params("format", c.Query("format"))
+	return ctrl.backend.FiberExport(c, m, conds, NewFiberCursorPagination(c, sort, q.Limit()), format)
+}
+
+func (ctrl fiberModelsCtrl) listQuery(c FiberCtx, m *psql.Model) (pagination.PaginationQuerySort, pagination.Sort) {
+	sorts := map[string]string{
+		m.ToColumnName("Id"): m.ToColumnName("Id"),
+	}
+	if f, ok := m.New().Interface().(Filterable); ok {
+		for field := range f.Filters() {
+			sorts[m.ToColumnName(field)] = m.ToColumnName(field)
+		}
+	}
+	sort := pagination.Sort{
+		AllowedSorts: sorts,
+		DefaultSort:  m.ToColumnName("Id"),
+		DefaultOrder: "asc",
+	}
+	q := pagination.PaginationQuerySort{
+		pagination.Pagination{
+			MaxPer:     50,
+			DefaultPer: 20,
+		},
+		pagination.Query{},
+		sort,
+	}
+	pagination.Bind(&q, c.QueryParser)
+	return q, sort
+}
+
+func (ctrl fiberModelsCtrl) listConditions(c FiberCtx, m *psql.Model) (conds Conditions, rank string, err error) {
+	if conds, err = ctrl.backend.FiberTenantConditions(c, m); err != nil {
+		return
+	}
+	rank = ctrl.backend.AddSearch(&conds, m, c.Query("query"))
+	if ctrl.softDelete(m) {
+		if c.Query("status") == "deleted" {
+			conds.Add(fmt.Sprintf("%s IS NOT NULL", m.ToColumnName("DeletedAt")))
+		} else {
+			conds.Add(fmt.Sprintf("%s IS NULL", m.ToColumnName("DeletedAt")))
+		}
+	}
+	filters, err := ctrl.backend.FiberFilterConditions(c, m)
+	if err != nil {
+		return
+	}
+	conds.Merge(filters)
+	return
+}
+
+func (ctrl fiberModelsCtrl) Show(c FiberCtx) error {
+	m := ctrl.model()
+	record := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), record); err != nil {
+		return err
+	}
+	FiberSetETag(c, record)
+	return ctrl.backend.fiberShow(c, m, record)
+}
+
+func (ctrl fiberModelsCtrl) Create(c FiberCtx) error {
+	m := ctrl.model()
+	record := m.New().Interface()
+	if c.Get("Content-Length") == "0" {
+		return c.JSON(serialize(record, "show"))
+	}
+	params := ctrl.params(m, "create")
+	body, err := ctrl.backend.FiberInput(c, record, params)
+	if err != nil {
+		return err
+	}
+	changes := m.MustAssign(
+		record,
+		m.Permit(params...).Filter(body),
+		m.CreatedAt(),
+		m.UpdatedAt(),
+	)
+	tenant, err := ctrl.backend.fiberTenantChanges(c, m, record)
+	if err != nil {
+		return err
+	}
+	changes = append(changes, tenant...)
+	err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.CreateInTransaction(tx, m, record, changes)
+	})
+	if err != nil {
+		return err
+	}
+	FiberSetETag(c, record)
+	return ctrl.backend.fiberShow(c, m, record)
+}
+
+// Update applies the request body as JSON merge patch (PATCH) or full
+// replacement (PUT), see AssignUpdate(). Like Create, form-urlencoded and
+// multipart bodies are accepted, see FiberInput().
+func (ctrl fiberModelsCtrl) Update(c FiberCtx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	m := ctrl.model()
+	current := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, id, current); err != nil {
+		return err
+	}
+	if err := FiberCheckIfMatch(c, current); err != nil {
+		return err
+	}
+	params := ctrl.params(m, "update")
+	body, err := ctrl.backend.FiberInput(c, current, params)
+	if err != nil {
+		return err
+	}
+	record, changes, err := ctrl.backend.AssignUpdate(m, current, body, params, c.Method() == "PUT")
+	if err != nil {
+		return err
+	}
+	err = ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.UpdateInTransaction(tx, m, current, record, changes)
+	})
+	if err != nil {
+		return fiberSetErrorETag(c, err)
+	}
+	FiberSetETag(c, record)
+	return ctrl.backend.fiberShow(c, m, record)
+}
+
+func (ctrl fiberModelsCtrl) Restore(c FiberCtx) error {
+	m := ctrl.model()
+	record := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), record); err != nil {
+		return err
+	}
+	err := ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.RestoreInTransaction(tx, m, record)
+	})
+	if err != nil {
+		return fiberSetErrorETag(c, err)
+	}
+	return ctrl.Show(c)
+}
+
+// Destroy soft deletes the record if the model has DeletedAt field,
+// otherwise the record is deleted permanently. Like Update, the If-Match
+// header is checked against the entity tag of the record.
+func (ctrl fiberModelsCtrl) Destroy(c FiberCtx) error {
+	m := ctrl.model()
+	record := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, c.Params("id"), record); err != nil {
+		return err
+	}
+	if err := FiberCheckIfMatch(c, record); err != nil {
+		return err
+	}
+	err := ctrl.backend.Transaction(m, func(tx *psql.Tx) error {
+		return ctrl.backend.DestroyInTransaction(tx, m, record, false)
+	})
+	if err != nil {
+		return err
+	}
+	return ctrl.backend.fiberShow(c, m, record)
+}
+
+// Purge deletes the record and its dependents permanently.
+func (ctrl fiberModelsCtrl) Purge(c FiberCtx) error {
+	id, _ := strconv.Atoi(c.Params("id"))
+	m := ctrl.model()
+	record := m.New().Interface()
+	if err := ctrl.backend.FiberFind(c, m, id, record); err != nil {
+		return err
+	}
+	if err := FiberCheckIfMatch(c, record); err != nil {
+		return err
+	}
+	if err := ctrl.backend.DeletePermanently(m, id); err != nil {
+		return err
+	}
+	return c.SendStatus(204)
+}
+
+// Import inserts records from JSON array or CSV, see FiberImport().
+func (ctrl fiberModelsCtrl) Import(c FiberCtx) error {
+	m := ctrl.model()
+	result, err := ctrl.backend.FiberImport(c, m, ctrl.params(m, "create"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(result)
+}
+
+// params returns permitted params of the model if it implements HasParams,
+// otherwise all fields except Id, TenantId, Version, CreatedAt, UpdatedAt
+// and DeletedAt.
+func (ctrl fiberModelsCtrl) params(m *psql.Model, action string) []string {
+	record := m.New().Interface()
+	if record, ok := record.(HasParams); ok {
+		return record.Params(action)
+	}
+	var params []string
+	typ := reflect.TypeOf(record).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || f.Anonymous {
+			continue
+		}
+		switch f.Name {
+		case "Id", "TenantId", "Version", "CreatedAt", "UpdatedAt", "DeletedAt":
+			continue
+		}
+		params = append(params, f.Name)
+	}
+	return params
+}
@@ -0,0 +1,70 @@
+package backend
+
+import "encoding/json"
+
+// NewFiberSchemasCtrl creates a controller for fiber serving JSON Schemas of
+// the registered models (see ModelSchema), for example to generate forms.
+func (backend *Backend) NewFiberSchemasCtrl() *fiberSchemasCtrl {
+	return &fiberSchemasCtrl{
+		backend: backend,
+	}
+}
+
+type fiberSchemasCtrl struct {
+	backend *Backend
+}
+
+func (ctrl fiberSchemasCtrl) List(c FiberCtx) error {
+	schemas := []jsonObject{}
+	for _, schema := range ctrl.backend.ModelSchemas() {
+		obj, err := ctrl.object(schema)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, obj)
+	}
+	b, err := json.Marshal(schemas)
+	if err != nil {
+		return err
+	}
+	return c.JSON(keepKeys{jsonObject{
+		keys:   []string{ctrl.backend.keyCase.fromGo("Schemas")},
+		values: []json.RawMessage{b},
+	}})
+}
+
+func (ctrl fiberSchemasCtrl) Show(c FiberCtx) error {
+	schema, ok := ctrl.backend.ModelSchema(c.Params("name"))
+	if !ok {
+		c.SendStatus(404)
+		return c.JSON(struct {
+			Message string
+		}{"Not Found"})
+	}
+	obj, err := ctrl.object(schema)
+	if err != nil {
+		return err
+	}
+	return c.JSON(keepKeys{obj})
+}
+
+// object returns schema with its keys in the key case, the keys of the JSON
+// Schema are kept.
+func (ctrl fiberSchemasCtrl) object(schema ModelSchema) (obj jsonObject, err error) {
+	for _, field := range []struct {
+		key   string
+		value interface{}
+	}{
+		{"Name", schema.Name},
+		{"Schema", schema.Schema},
+		{"Params", schema.Params},
+	} {
+		var b []byte
+		if b, err = json.Marshal(field.value); err != nil {
+			return
+		}
+		obj.keys = append(obj.keys, ctrl.backend.keyCase.fromGo(field.key))
+		obj.values = append(obj.values, b)
+	}
+	return
+}
@@ -19,15 +19,22 @@ func (ctrl fiberSessionsCtrl) Authenticate(c FiberCtx) error {
 			Message string
 		}{"Please Log In"})
 	}
+	if _, err := ctrl.backend.FiberTenantId(c); err != nil {
+		return err
+	}
 	return c.Next()
 }
 
 func (ctrl fiberSessionsCtrl) Me(c FiberCtx) error {
 	admin := ctrl.backend.FiberGetCurrentAdmin(c)
-	if a, ok := admin.(Serializable); ok {
-		return c.JSON(a.Serialize("me"))
+	if admin == nil {
+		return c.JSON(admin)
+	}
+	out, err := ctrl.backend.FiberSerialize(c, ctrl.backend.ModelByName(getName(c, "Admin")), "me", admin)
+	if err != nil {
+		return err
 	}
-	return c.JSON(admin)
+	return c.JSON(out[0])
 }
 
 func (ctrl fiberSessionsCtrl) SignIn(c FiberCtx) error {
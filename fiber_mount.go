@@ -0,0 +1,325 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// FiberMountOptions configures routes registered by MountFiber().
+	//
+	// Routes are named after the model and the controller method, like
+	// "Session.SignIn", "Admin.List" or "Post.Update". "Session.Authenticate"
+	// is the authentication middleware.
+	FiberMountOptions struct {
+		// Prefix of all routes, for example "/api".
+		Prefix string
+
+		// Path of the admin routes, "/admins" by default.
+		AdminsPath string
+
+		// Models to register generic model controllers for, mapping
+		// model names to their paths, for example {"Post": "/posts"}.
+		Models map[string]string
+
+		// Middlewares run after authentication and before the handler of
+		// each route that needs authentication.
+		Middlewares []FiberHandler
+
+		// Names of routes not to register. Name of a model disables all of
+		// its routes.
+		Disabled []string
+
+		// Handlers replacing the default handlers of the routes by name.
+		Handlers map[string]FiberHandler
+
+		// Title and version of the OpenAPI document, "API" and "1.0.0" by
+		// default.
+		Title, Version string
+
+		// Optional routes, which are not registered unless enabled: the
+		// OpenAPI document at /openapi.json (see OpenAPI()), the JSON
+		// Schemas of the models at /schemas (see ModelSchema), the admin
+		// web UI at /ui (see FiberAdminUI(), which needs and enables the
+		// OpenAPI document and the schemas), the HTML pages of the sessions
+		// and admins controllers at /html (see NewFiberHTMLAdminsCtrl()),
+		// authenticated with the session cookie, and GraphQL at /graphql
+		// (see NewFiberGraphQLCtrl()).
+		OpenAPI, Schemas, UI, HTML, GraphQL bool
+	}
+
+	// fiberRoute is a route registered by MountFiber(). Model and action
+	// are empty for routes not belonging to a controller.
+	fiberRoute struct {
+		name    string
+		model   string
+		action  string
+		method  string
+		path    string
+		public  bool
+		handler FiberHandler
+	}
+)
+
+var fiberCtxType = reflect.TypeOf((*FiberCtx)(nil)).Elem()
+
+// MountFiber registers routes of the sessions controller, the admins
+// controller and the generic controllers of options.Models to router, which
+// should be fiber.Router (*fiber.App or fiber.Group) of
+// github.com/gofiber/fiber/v2, and the optional routes enabled in options.
+// Sign-in, me, the OpenAPI document and the admin web UI are public, all
+// other routes need authentication. Responses and request bodies can be in
+// MessagePack or CBOR instead of JSON (see FiberNegotiate()). Other POST
+// routes that need authentication accept the Idempotency-Key header (see
+// FiberIdempotency()). Errors returned by the handlers, or their panics, are
+// responded with FiberHandleError(), like the ErrorHandler of the README does
+// for routes registered by hand, so the responses do not depend on the
+// ErrorHandler of the fiber app.
+func (backend *Backend) MountFiber(router interface{}, options FiberMountOptions) error {
+	add := reflect.ValueOf(router).MethodByName("Add")
+	if !add.IsValid() || !add.Type().IsVariadic() || add.Type().NumIn() != 3 {
+		return errors.New("router has no Add(method, path string, handlers ...Handler) method")
+	}
+	handlerType := add.Type().In(2).Elem()
+	if handlerType.Kind() != reflect.Func || handlerType.NumIn() != 1 || handlerType.NumOut() != 1 ||
+		!handlerType.In(0).Implements(fiberCtxType) {
+		return errors.New("router handler does not accept FiberCtx")
+	}
+	toHandler := func(h FiberHandler) reflect.Value {
+		h = backend.FiberNegotiate(h)
+		return reflect.MakeFunc(handlerType, func(args []reflect.Value) []reflect.Value {
+			out := reflect.New(handlerType.Out(0)).Elem()
+			c := args[0].Interface().(FiberCtx)
+			err := func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						if e, ok := r.(error); ok {
+							err = e
+						} else {
+							err = fmt.Errorf("%v", r)
+						}
+					}
+				}()
+				return h(c)
+			}()
+			if err != nil {
+				if err := backend.FiberHandleError(c, err); err != nil {
+					out.Set(reflect.ValueOf(err))
+				}
+			}
+			return []reflect.Value{out}
+		})
+	}
+
+	routes, err := backend.fiberRoutes(options)
+	if err != nil {
+		return err
+	}
+	authenticate := toHandler(options.handler("Session.Authenticate", backend.NewFiberSessionsCtrl().Authenticate))
+	htmlAuthenticate := toHandler(options.handler("HTMLSession.Authenticate",
+		backend.NewFiberHTMLSessionsCtrl(options.htmlPath()).Authenticate))
+	var middlewares []reflect.Value
+	for _, m := range options.Middlewares {
+		middlewares = append(middlewares, toHandler(m))
+	}
+	for _, route := range routes {
+		args := []reflect.Value{reflect.ValueOf(route.method), reflect.ValueOf(route.path)}
+		if !route.public {
+			if route.html() {
+				args = append(args, htmlAuthenticate)
+			} else {
+				args = append(args, authenticate)
+			}
+			args = append(args, middlewares...)
+		}
+		h := options.handler(route.name, route.handler)
+		if !route.public && !route.html() && route.method == "POST" && route.model != "GraphQL" {
+			h = backend.FiberIdempotency(route.name, h)
+		}
+		args = append(args, toHandler(h))
+		add.Call(args)
+	}
+	return nil
+}
+
+// MustMountFiber is like MountFiber but panics if routes cannot be
+// registered.
+func (backend *Backend) MustMountFiber(router interface{}, options FiberMountOptions) {
+	if err := backend.MountFiber(router, options); err != nil {
+		panic(err)
+	}
+}
+
+// fiberRoutes returns the enabled routes of MountFiber() with prefixed paths.
+func (backend *Backend) fiberRoutes(options FiberMountOptions) ([]fiberRoute, error) {
+	sc := backend.NewFiberSessionsCtrl()
+	var routes []fiberRoute
+	if options.OpenAPI || options.UI {
+		routes = append(routes, fiberRoute{"OpenAPI", "", "", "GET", "/openapi.json", true, func(c FiberCtx) error {
+			doc, err := backend.OpenAPI(options)
+			if err != nil {
+				return err
+			}
+			return c.JSON(keepKeys{doc})
+		}})
+	}
+	if options.UI {
+		ui := backend.FiberAdminUI(options.Prefix)
+		routes = append(routes,
+			fiberRoute{"UI", "", "", "GET", "/ui", true, ui},
+			fiberRoute{"UI", "", "", "GET", "/ui/*", true, ui},
+		)
+	}
+	routes = append(routes, fiberResourceRoutes("Session", "", true, []fiberRoute{
+		{name: "SignIn", method: "POST", path: "/sign-in", handler: sc.SignIn},
+		{name: "Me", method: "GET", path: "/me", handler: sc.Me},
+	})...)
+	routes = append(routes, fiberResourceRoutes("Session", "", false, []fiberRoute{
+		{name: "SignOut", method: "POST", path: "/sign-out", handler: sc.SignOut},
+	})...)
+
+	adminsPath := options.AdminsPath
+	if adminsPath == "" {
+		adminsPath = "/admins"
+	}
+	ac := backend.NewFiberAdminsCtrl()
+	routes = append(routes, fiberResourceRoutes("Admin", adminsPath, false, []fiberRoute{
+		{name: "List", method: "GET", path: "", handler: ac.List},
+		{name: "Export", method: "GET", path: "/export.:format", handler: ac.Export},
+		{name: "Import", method: "POST", path: "/import", handler: ac.Import},
+		{name: "BulkUpdate", method: "POST", path: "/bulk/update", handler: ac.BulkUpdate},
+		{name: "BulkDestroy", method: "POST", path: "/bulk/destroy", handler: ac.BulkDestroy},
+		{name: "BulkRestore", method: "POST", path: "/bulk/restore", handler: ac.BulkRestore},
+		{name: "Show", method: "GET", path: "/:id", handler: ac.Show},
+		{name: "Create", method: "POST", path: "", handler: ac.Create},
+		{name: "Update", method: "PUT", path: "/:id", handler: ac.Update},
+		{name: "Update", method: "PATCH", path: "/:id", handler: ac.Update},
+		{name: "Destroy", method: "DELETE", path: "/:id", handler: ac.Destroy},
+		{name: "Purge", method: "DELETE", path: "/:id/permanently", handler: ac.Purge},
+		{name: "Restore", method: "POST", path: "/:id", handler: ac.Restore},
+		{name: "Sessions", method: "GET", path: "/:id/sessions", handler: ac.Sessions},
+		{name: "RevokeSessions", method: "DELETE", path: "/:id/sessions", handler: ac.RevokeSessions},
+		{name: "RevokeSession", method: "DELETE", path: "/:id/sessions/:sessionId", handler: ac.RevokeSession},
+	})...)
+
+	if options.HTML {
+		hsc := backend.NewFiberHTMLSessionsCtrl(options.htmlPath())
+		routes = append(routes, fiberResourceRoutes("HTMLSession", "/html", true, []fiberRoute{
+			{name: "SignInForm", method: "GET", path: "/sign-in", handler: hsc.SignInForm},
+			{name: "SignIn", method: "POST", path: "/sign-in", handler: hsc.SignIn},
+		})...)
+		routes = append(routes, fiberResourceRoutes("HTMLSession", "/html", false, []fiberRoute{
+			{name: "SignOut", method: "POST", path: "/sign-out", handler: hsc.SignOut},
+		})...)
+		hac := backend.NewFiberHTMLAdminsCtrl(options.htmlPath())
+		routes = append(routes, fiberResourceRoutes("HTMLAdmin", "/html/admins", false, []fiberRoute{
+			{name: "List", method: "GET", path: "", handler: hac.List},
+			{name: "New", method: "GET", path: "/new", handler: hac.New},
+			{name: "Create", method: "POST", path: "", handler: hac.Create},
+			{name: "Edit", method: "GET", path: "/:id", handler: hac.Edit},
+			{name: "Update", method: "POST", path: "/:id", handler: hac.Update},
+			{name: "Destroy", method: "POST", path: "/:id/destroy", handler: hac.Destroy},
+		})...)
+	}
+
+	if options.Schemas || options.UI {
+		schc := backend.NewFiberSchemasCtrl()
+		routes = append(routes, fiberResourceRoutes("Schema", "/schemas", false, []fiberRoute{
+			{name: "List", method: "GET", path: "", handler: schc.List},
+			{name: "Show", method: "GET", path: "/:name", handler: schc.Show},
+		})...)
+	}
+
+	var names []string
+	for name := range options.Models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m := backend.ModelByName(name)
+		if m == nil {
+			return nil, errors.New("no model named " + name)
+		}
+		mc := backend.NewFiberModelsCtrl(name)
+		actions := []fiberRoute{
+			{name: "List", method: "GET", path: "", handler: mc.List},
+			{name: "Export", method: "GET", path: "/export.:format", handler: mc.Export},
+			{name: "Import", method: "POST", path: "/import", handler: mc.Import},
+			{name: "Show", method: "GET", path: "/:id", handler: mc.Show},
+			{name: "Create", method: "POST", path: "", handler: mc.Create},
+			{name: "Update", method: "PUT", path: "/:id", handler: mc.Update},
+			{name: "Update", method: "PATCH", path: "/:id", handler: mc.Update},
+			{name: "Destroy", method: "DELETE", path: "/:id", handler: mc.Destroy},
+		}
+		if mc.softDelete(m) {
+			actions = append(actions,
+				fiberRoute{name: "Purge", method: "DELETE", path: "/:id/permanently", handler: mc.Purge},
+				fiberRoute{name: "Restore", method: "POST", path: "/:id", handler: mc.Restore},
+			)
+		}
+		routes = append(routes, fiberResourceRoutes(name, options.Models[name], false, actions)...)
+	}
+
+	var enabled []fiberRoute
+	for _, route := range routes {
+		if !options.disabled(route.name) {
+			route.path = options.Prefix + route.path
+			enabled = append(enabled, route)
+		}
+	}
+
+	// GraphQL resolves fields with the handlers of the enabled routes.
+	if options.GraphQL && !options.disabled("GraphQL.Execute") {
+		handlers := map[string]FiberHandler{}
+		for _, route := range enabled {
+			if !route.html() && backend.ModelByName(route.model) != nil {
+				handlers[route.name] = options.handler(route.name, route.handler)
+			}
+		}
+		gc := backend.NewFiberGraphQLCtrl(handlers)
+		enabled = append(enabled, fiberResourceRoutes("GraphQL", options.Prefix+"/graphql", false, []fiberRoute{
+			{name: "Execute", method: "GET", path: "", handler: gc.Execute},
+			{name: "Execute", method: "POST", path: "", handler: gc.Execute},
+		})...)
+	}
+	return enabled, nil
+}
+
+func fiberResourceRoutes(model, path string, public bool, actions []fiberRoute) []fiberRoute {
+	routes := make([]fiberRoute, len(actions))
+	for i, action := range actions {
+		routes[i] = fiberRoute{model + "." + action.name, model, action.name, action.method,
+			path + action.path, public, action.handler}
+	}
+	return routes
+}
+
+// html returns true if route belongs to the HTML controllers, which are
+// authenticated with the session cookie and not in the OpenAPI document.
+func (route fiberRoute) html() bool {
+	return strings.HasPrefix(route.model, "HTML")
+}
+
+// htmlPath returns path of the routes of the HTML controllers.
+func (options FiberMountOptions) htmlPath() string {
+	return options.Prefix + "/html"
+}
+
+func (options FiberMountOptions) handler(name string, defaultHandler FiberHandler) FiberHandler {
+	if h, ok := options.Handlers[name]; ok && h != nil {
+		return h
+	}
+	return defaultHandler
+}
+
+func (options FiberMountOptions) disabled(name string) bool {
+	for _, d := range options.Disabled {
+		if d == name || strings.HasPrefix(name, d+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,236 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gopsql/bcrypt"
+	"github.com/gopsql/psql"
+)
+
+var bcryptPasswordType = reflect.TypeOf((*bcrypt.Password)(nil)).Elem()
+
+// jsonObject is a JSON object that keeps the order of its keys.
+type jsonObject struct {
+	keys   []string
+	values []json.RawMessage
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(o.keys[i])
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(o.values[i])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// FiberSerialize serializes records (pointers to structs of model m) with
+// the typ view and applies the sparse fieldset and includes in the query
+// string of fiber context:
+//
+//	?fields=Id,Name
+//	?include=Sessions
+//
+// Fields are the names of the serialized fields. Includes are names of the
+// relations returned by Relations() of the model, related records of all
+// records are queried at once for each relation, and added to the output
+// serialized with the "list" view. Unknown includes result in InputErrors.
+func (backend Backend) FiberSerialize(c FiberCtx, m *psql.Model, typ string, records ...interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(records))
+	for i, record := range records {
+		out[i] = serialize(record, typ)
+	}
+	fields := splitList(c.Query("fields"))
+	includes := splitList(c.Query("include"))
+	if len(fields) == 0 && len(includes) == 0 {
+		return out, nil
+	}
+
+	var relations map[string]Relation
+	if r, ok := m.New().Interface().(HasRelations); ok {
+		relations = r.Relations()
+	}
+	var names []string
+	var related []map[int64][]interface{}
+	for _, include := range includes {
+		name := findRelation(relations, include)
+		if name == "" {
+			return nil, InputErrors{{
+				FullName: "include",
+				Name:     "include",
+				Kind:     "string",
+				Type:     "invalid",
+				Param:    include,
+			}}
+		}
+		rows, err := backend.findRelated(records, relations[name])
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		related = append(related, rows)
+	}
+
+	for i, record := range records {
+		b, err := json.Marshal(out[i])
+		if err != nil {
+			return nil, err
+		}
+		keys, values, err := jsonObjectFields(b)
+		if err != nil {
+			continue // not an object, leave as is
+		}
+		var obj jsonObject
+		for j, key := range keys {
+			if len(fields) == 0 || findParam(fields, key) != "" {
+				obj.keys = append(obj.keys, key)
+				obj.values = append(obj.values, values[j])
+			}
+		}
+		id, _ := toInt64(fieldInterface(reflect.Indirect(reflect.ValueOf(record)), "Id"))
+		for j, name := range names {
+			rows := related[j][id]
+			if rows == nil {
+				rows = []interface{}{}
+			}
+			value, err := json.Marshal(rows)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, name)
+			obj.values = append(obj.values, value)
+		}
+		out[i] = obj
+	}
+	return out, nil
+}
+
+// fiberShow responds with the "show" view of record, see FiberSerialize().
+func (backend Backend) fiberShow(c FiberCtx, m *psql.Model, record interface{}) error {
+	out, err := backend.FiberSerialize(c, m, "show", record)
+	if err != nil {
+		return err
+	}
+	return c.JSON(out[0])
+}
+
+// findRelated returns records related to records grouped by their foreign
+// keys.
+func (backend Backend) findRelated(records []interface{}, relation Relation) (map[int64][]interface{}, error) {
+	rm := backend.ModelByName(relation.Model)
+	if rm == nil {
+		return nil, InputErrors{NewInputError("include", "invalid")}
+	}
+	var ids []interface{}
+	for _, record := range records {
+		if id := fieldInterface(reflect.Indirect(reflect.ValueOf(record)), "Id"); id != nil {
+			ids = append(ids, id)
+		}
+	}
+	out := map[int64][]interface{}{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var conds Conditions
+	conds.AddIn(rm.ToColumnName(relation.ForeignKey), ids...)
+	rows := rm.NewSlice()
+	err := rm.Find().Where(conds.String(), conds.Args()...).OrderBy(rm.ToColumnName("Id")).Query(rows.Interface())
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < rows.Elem().Len(); i++ {
+		row := rows.Elem().Index(i)
+		fk, _ := toInt64(fieldInterface(row, relation.ForeignKey))
+		out[fk] = append(out[fk], serialize(row.Addr().Interface(), "list"))
+	}
+	return out, nil
+}
+
+// findRelation returns the name of the relation matching name
+// case-insensitively.
+func findRelation(relations map[string]Relation, name string) string {
+	var names []string
+	for n := range relations {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return findParam(names, name)
+}
+
+// splitList splits comma-separated list and removes empty items.
+func splitList(s string) (out []string) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return
+}
+
+// writeOnlyFields returns names of the fields of i that must not be included
+// in responses: fields returned by WriteOnlyFields() if i implements
+// HasWriteOnlyFields, fields with the backend:"writeonly" tag and fields of
+// bcrypt.Password type, including the ones of embedded structs.
+func writeOnlyFields(i interface{}) (fields []string) {
+	if w, ok := i.(HasWriteOnlyFields); ok {
+		fields = append(fields, w.WriteOnlyFields()...)
+	}
+	typ := reflect.TypeOf(i)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return
+	}
+	return append(fields, writeOnlyStructFields(typ)...)
+}
+
+func writeOnlyStructFields(typ reflect.Type) (fields []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, writeOnlyStructFields(f.Type)...)
+			continue
+		}
+		if f.Tag.Get("backend") == "writeonly" || f.Type == bcryptPasswordType {
+			fields = append(fields, f.Name)
+		}
+	}
+	return
+}
+
+// withoutFields returns v as JSON object without the fields (case
+// insensitive), or v as is if there are no fields to remove or v is not a
+// JSON object.
+func withoutFields(v interface{}, fields []string) interface{} {
+	if len(fields) == 0 {
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	keys, values, err := jsonObjectFields(b)
+	if err != nil {
+		return v
+	}
+	var obj jsonObject
+	for i, key := range keys {
+		if findParam(fields, key) == "" {
+			obj.keys = append(obj.keys, key)
+			obj.values = append(obj.values, values[i])
+		}
+	}
+	return obj
+}
@@ -0,0 +1,183 @@
+package backend
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gopsql/psql"
+)
+
+// Supported filter operators.
+var filterOperators = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"gt":   ">",
+	"gte":  ">=",
+	"lt":   "<",
+	"lte":  "<=",
+	"in":   "IN",
+	"nin":  "NOT IN",
+	"like": "LIKE",
+	"null": "IS NULL",
+}
+
+var filterKeyRegexp = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// FiberFilterConditions parses filters from the query string of a fiber
+// context and returns SQL conditions for model m. Filters look like:
+//
+//	filter[name]=admin
+//	filter[name][in]=admin,root
+//	filter[created_at][gte]=2021-01-01
+//	filter[deleted_at][null]=false
+//
+// Only the fields and operators returned by the Filters() method of the model
+// are allowed, otherwise InputErrors is returned.
+func (backend Backend) FiberFilterConditions(c FiberCtx, m *psql.Model) (conds Conditions, err error) {
+	var keys []string
+	for key := range c.Queries() {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	allowed := map[string][]string{}
+	if f, ok := m.New().Interface().(Filterable); ok {
+		allowed = f.Filters()
+	}
+	fields := map[string]string{} // column name => field name
+	for field := range allowed {
+		fields[m.ToColumnName(field)] = field
+	}
+	typ := reflect.TypeOf(m.New().Interface()).Elem()
+
+	var errs InputErrors
+	for _, key := range keys {
+		matches := filterKeyRegexp.FindStringSubmatch(key)
+		if matches == nil {
+			errs = append(errs, filterInputError(key, "", "", "invalid"))
+			continue
+		}
+		column, op := matches[1], matches[2]
+		if op == "" {
+			op = "eq"
+		}
+		field, ok := fields[column]
+		if !ok {
+			errs = append(errs, filterInputError(key, column, op, "filter"))
+			continue
+		}
+		if _, ok := filterOperators[op]; !ok || !containsString(allowed[field], op) {
+			errs = append(errs, filterInputError(key, column, op, "operator"))
+			continue
+		}
+		sf, ok := typ.FieldByName(field)
+		if !ok {
+			errs = append(errs, filterInputError(key, column, op, "filter"))
+			continue
+		}
+		cond, args, err := filterCondition(m, column, op, sf.Type, c.Query(key))
+		if err != nil {
+			ierr := filterInputError(key, column, op, "invalid")
+			ierr.Kind = sf.Type.Kind().String()
+			errs = append(errs, ierr)
+			continue
+		}
+		conds.Add(cond, args...)
+	}
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
+
+func filterCondition(m *psql.Model, column, op string, typ reflect.Type, value string) (string, []interface{}, error) {
+	switch op {
+	case "null":
+		isNull, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, err
+		}
+		if isNull {
+			return fmt.Sprintf("%s IS NULL", column), nil, nil
+		}
+		return fmt.Sprintf("%s IS NOT NULL", column), nil, nil
+	case "like":
+		return fmt.Sprintf(`%s %s $? ESCAPE '\'`, column, likeOperator(m)), []interface{}{"%" + escapeLike(value) + "%"}, nil
+	case "in", "nin":
+		var args []interface{}
+		var placeholders []string
+		for _, s := range strings.Split(value, ",") {
+			v, err := parseStringValue(typ, s)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, v)
+			placeholders = append(placeholders, "$?")
+		}
+		return fmt.Sprintf("%s %s (%s)", column, filterOperators[op], strings.Join(placeholders, ", ")), args, nil
+	}
+	v, err := parseStringValue(typ, value)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s %s $?", column, filterOperators[op]), []interface{}{v}, nil
+}
+
+// parseStringValue converts string to the value of type typ.
+func parseStringValue(typ reflect.Type, value string) (interface{}, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid time: %s", value)
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(value, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+func filterInputError(key, column, op, errType string) InputError {
+	return InputError{
+		FullName: key,
+		Name:     column,
+		Kind:     "string",
+		Type:     errType,
+		Param:    op,
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
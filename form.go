@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxFormMemory is the maximum memory of the parts of multipart forms, the
+// rest of the files are stored in temporary files.
+const maxFormMemory = 32 << 20
+
+// FiberInput returns request body of fiber context as JSON object for
+// Permit().Filter() or AssignUpdate(). Bodies of Content-Type
+// application/x-www-form-urlencoded and multipart/form-data are converted
+// with the params, coercing values to the types of the fields of record.
+// Files of multipart forms are assigned to string fields as text and to
+// []byte fields. Other bodies are returned as they are.
+func (backend Backend) FiberInput(c FiberCtx, record interface{}, params []string) ([]byte, error) {
+	form, ok, err := backend.fiberForm(c, record)
+	if err != nil {
+		return nil, NewInputErrors("Body", "invalid")
+	}
+	if !ok {
+		return c.Body(), nil
+	}
+	return formToJSON(record, params, form)
+}
+
+// fiberForm parses form-urlencoded or multipart request body with names in
+// the key case (see SetKeyCase()). False is returned for other bodies.
+// Files of multipart forms are added to the values, base64-encoded for
+// []byte fields of record.
+func (backend Backend) fiberForm(c FiberCtx, record interface{}) (url.Values, bool, error) {
+	ctype, params, _ := mime.ParseMediaType(c.Get("Content-Type"))
+	form := url.Values{}
+	switch ctype {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(c.Body()))
+		if err != nil {
+			return nil, true, err
+		}
+		for key, v := range values {
+			form[backend.keyCase.toGo(key)] = v
+		}
+		return form, true, nil
+	case "multipart/form-data":
+	default:
+		return nil, false, nil
+	}
+	r := multipart.NewReader(bytes.NewReader(c.Body()), params["boundary"])
+	mf, err := r.ReadForm(maxFormMemory)
+	if err != nil {
+		return nil, true, err
+	}
+	defer mf.RemoveAll()
+	for key, v := range mf.Value {
+		form[backend.keyCase.toGo(key)] = v
+	}
+	typ := reflect.TypeOf(record).Elem()
+	for key, files := range mf.File {
+		key = backend.keyCase.toGo(key)
+		for _, fh := range files {
+			f, err := fh.Open()
+			if err != nil {
+				return nil, true, err
+			}
+			b, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, true, err
+			}
+			value := string(b)
+			field, ok := typ.FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, key) })
+			if ok && field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Uint8 {
+				value = base64.StdEncoding.EncodeToString(b)
+			}
+			form.Add(key, value)
+		}
+	}
+	return form, true, nil
+}
+
+// formToJSON converts form values of the permitted params to JSON object
+// for Permit().Filter() or AssignUpdate(), coercing the values to the types
+// of the fields of record. Params not in form are left out. Values that
+// cannot be converted result in InputErrors.
+func formToJSON(record interface{}, params []string, form url.Values) ([]byte, error) {
+	typ := reflect.TypeOf(record).Elem()
+	obj := map[string]interface{}{}
+	var errs InputErrors
+	for key, values := range form {
+		param := findParam(params, key)
+		if param == "" || len(values) == 0 {
+			continue
+		}
+		f, ok := typ.FieldByName(param)
+		if !ok {
+			continue
+		}
+		value, err := formValue(f.Type, values)
+		if err != nil {
+			errs = append(errs, NewInputError(param, "invalid"))
+			continue
+		}
+		obj[param] = value
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return json.Marshal(obj)
+}
+
+// formValue converts values to a value that is marshaled to JSON of type
+// typ. The last value is used unless typ is slice.
+func formValue(typ reflect.Type, values []string) (interface{}, error) {
+	value := values[len(values)-1]
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if value == "" {
+			return nil, nil
+		}
+		return formValue(typ.Elem(), values)
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return value, nil
+		}
+		out := []interface{}{}
+		for _, v := range values {
+			for _, v := range strings.Split(v, ",") {
+				if v = strings.TrimSpace(v); v == "" {
+					continue
+				}
+				elem, err := formValue(typ.Elem(), []string{v})
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, elem)
+			}
+		}
+		return out, nil
+	case reflect.Bool:
+		if value == "on" {
+			return true, nil
+		}
+		if value == "" || value == "off" {
+			return false, nil
+		}
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.ParseInt(value, 10, typ.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.ParseUint(value, 10, typ.Bits())
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(value, typ.Bits())
+	}
+	return value, nil
+}
@@ -0,0 +1,498 @@
+package backend
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Minimal GraphQL parser supporting operations (query and mutation) with
+// variables, fields with aliases, arguments, selection sets and fragments.
+// Fragment spreads and inline fragments are expanded into the fields of the
+// selection sets, type conditions are not checked since all types of the
+// schema are object types. Directives are not supported.
+type (
+	gqlOperation struct {
+		typ        string // query or mutation
+		name       string
+		variables  map[string]interface{} // default values
+		selections []gqlField
+	}
+
+	gqlField struct {
+		alias      string
+		name       string
+		arguments  map[string]interface{}
+		selections []gqlField
+		fragment   string // name of fragment spread, expanded after parsing
+	}
+
+	// gqlVariable refers to variable by name in values.
+	gqlVariable string
+
+	gqlSyntaxError struct {
+		message string
+		pos     int
+	}
+
+	gqlParser struct {
+		src       string
+		pos       int
+		fragments map[string][]gqlField
+	}
+)
+
+func (err gqlSyntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error: %s at position %d", err.message, err.pos)
+}
+
+// key returns the key of the field in the response.
+func (f gqlField) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// parseGraphQL parses GraphQL document into operations.
+func parseGraphQL(src string) (operations []gqlOperation, err error) {
+	p := &gqlParser{src: src, fragments: map[string][]gqlField{}}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(gqlSyntaxError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	for p.skip(); p.pos < len(p.src); p.skip() {
+		if p.keyword("fragment") {
+			p.fragment()
+			continue
+		}
+		operations = append(operations, p.operation())
+	}
+	if len(operations) == 0 {
+		p.fail("empty document")
+	}
+	for i := range operations {
+		operations[i].selections, err = expandFragments(operations[i].selections, p.fragments, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// expandFragments replaces fragment spreads in fields with the fields of
+// the fragments, and merges fields of the same response key.
+func expandFragments(fields []gqlField, fragments map[string][]gqlField, spreading []string) (out []gqlField, err error) {
+	for _, f := range fields {
+		if f.fragment != "" {
+			selections, ok := fragments[f.fragment]
+			if !ok {
+				return nil, fmt.Errorf("Unknown fragment %q.", f.fragment)
+			}
+			if containsString(spreading, f.fragment) {
+				return nil, fmt.Errorf("Cannot spread fragment %q within itself.", f.fragment)
+			}
+			expanded, err := expandFragments(selections, fragments, append(spreading, f.fragment))
+			if err != nil {
+				return nil, err
+			}
+			out = mergeFields(out, expanded...)
+			continue
+		}
+		if f.selections != nil {
+			if f.selections, err = expandFragments(f.selections, fragments, spreading); err != nil {
+				return nil, err
+			}
+		}
+		out = mergeFields(out, f)
+	}
+	return
+}
+
+// mergeFields appends more to fields, merging selections of the fields of
+// the same response key.
+func mergeFields(fields []gqlField, more ...gqlField) []gqlField {
+	for _, f := range more {
+		merged := false
+		for i := range fields {
+			if fields[i].key() == f.key() {
+				fields[i].selections = mergeFields(fields[i].selections, f.selections...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (p *gqlParser) fail(format string, args ...interface{}) {
+	panic(gqlSyntaxError{fmt.Sprintf(format, args...), p.pos})
+}
+
+// skip skips white spaces, commas and comments.
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			p.pos++
+		case '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			if strings.HasPrefix(p.src[p.pos:], "\ufeff") {
+				p.pos += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+// peek returns the next character after white spaces, or 0 at the end.
+func (p *gqlParser) peek() byte {
+	p.skip()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *gqlParser) expect(c byte) {
+	if p.peek() != c {
+		p.fail("expected %q", c)
+	}
+	p.pos++
+}
+
+func (p *gqlParser) name() string {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if isNameChar(c) && (p.pos > start || c < '0' || c > '9') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if start == p.pos {
+		p.fail("expected name")
+	}
+	return p.src[start:p.pos]
+}
+
+// keyword returns true if the next name is word.
+func (p *gqlParser) keyword(word string) bool {
+	p.skip()
+	if !strings.HasPrefix(p.src[p.pos:], word) {
+		return false
+	}
+	end := p.pos + len(word)
+	return end == len(p.src) || !isNameChar(p.src[end])
+}
+
+// fragment parses fragment definition.
+func (p *gqlParser) fragment() {
+	p.pos += len("fragment")
+	name := p.name()
+	if name == "on" {
+		p.fail("unexpected \"on\"")
+	}
+	if !p.keyword("on") {
+		p.fail("expected \"on\"")
+	}
+	p.pos += len("on")
+	p.name()
+	p.directives()
+	if _, ok := p.fragments[name]; ok {
+		p.fail("duplicate fragment %q", name)
+	}
+	p.fragments[name] = p.selectionSet()
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func (p *gqlParser) operation() gqlOperation {
+	op := gqlOperation{typ: "query"}
+	if p.peek() != '{' {
+		switch typ := p.name(); typ {
+		case "query", "mutation":
+			op.typ = typ
+		case "subscription":
+			p.fail("subscriptions are not supported")
+		default:
+			p.fail("unexpected %q", typ)
+		}
+		if c := p.peek(); c != '{' && c != '(' {
+			op.name = p.name()
+		}
+		if p.peek() == '(' {
+			op.variables = p.variableDefinitions()
+		}
+	}
+	p.directives()
+	op.selections = p.selectionSet()
+	return op
+}
+
+func (p *gqlParser) variableDefinitions() map[string]interface{} {
+	defaults := map[string]interface{}{}
+	p.expect('(')
+	for p.peek() != ')' {
+		p.expect('$')
+		name := p.name()
+		p.expect(':')
+		p.typeReference()
+		if p.peek() == '=' {
+			p.pos++
+			defaults[name] = p.value(true)
+		}
+	}
+	p.pos++
+	return defaults
+}
+
+func (p *gqlParser) typeReference() {
+	if p.peek() == '[' {
+		p.pos++
+		p.typeReference()
+		p.expect(']')
+	} else {
+		p.name()
+	}
+	if p.peek() == '!' {
+		p.pos++
+	}
+}
+
+func (p *gqlParser) directives() {
+	if p.peek() == '@' {
+		p.fail("directives are not supported")
+	}
+}
+
+func (p *gqlParser) selectionSet() (fields []gqlField) {
+	p.expect('{')
+	for p.peek() != '}' {
+		if p.peek() == 0 {
+			p.fail("unexpected end of document")
+		}
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			p.pos += len("...")
+			if p.keyword("on") {
+				p.pos += len("on")
+				p.name()
+			} else if p.peek() != '{' && p.peek() != '@' {
+				fields = append(fields, gqlField{fragment: p.name()})
+				p.directives()
+				continue
+			}
+			p.directives()
+			fields = append(fields, p.selectionSet()...)
+			continue
+		}
+		fields = append(fields, p.field())
+	}
+	p.pos++
+	if len(fields) == 0 {
+		p.fail("empty selection set")
+	}
+	return
+}
+
+func (p *gqlParser) field() gqlField {
+	f := gqlField{name: p.name()}
+	if p.peek() == ':' {
+		p.pos++
+		f.alias, f.name = f.name, p.name()
+	}
+	if p.peek() == '(' {
+		p.pos++
+		f.arguments = map[string]interface{}{}
+		for p.peek() != ')' {
+			name := p.name()
+			p.expect(':')
+			f.arguments[name] = p.value(false)
+		}
+		p.pos++
+	}
+	p.directives()
+	if p.peek() == '{' {
+		f.selections = p.selectionSet()
+	}
+	return f
+}
+
+// value parses value. Variables are not allowed in constant values.
+func (p *gqlParser) value(constant bool) interface{} {
+	switch c := p.peek(); {
+	case c == '$':
+		if constant {
+			p.fail("unexpected variable")
+		}
+		p.pos++
+		return gqlVariable(p.name())
+	case c == '[':
+		p.pos++
+		list := []interface{}{}
+		for p.peek() != ']' {
+			if p.peek() == 0 {
+				p.fail("unexpected end of document")
+			}
+			list = append(list, p.value(constant))
+		}
+		p.pos++
+		return list
+	case c == '{':
+		p.pos++
+		obj := map[string]interface{}{}
+		for p.peek() != '}' {
+			name := p.name()
+			p.expect(':')
+			obj[name] = p.value(constant)
+		}
+		p.pos++
+		return obj
+	case c == '"':
+		return p.string()
+	case c == '-' || c >= '0' && c <= '9':
+		return p.number()
+	case c == 0:
+		p.fail("unexpected end of document")
+	}
+	switch name := p.name(); name {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	default:
+		return name // enum value
+	}
+}
+
+func (p *gqlParser) number() interface{} {
+	start := p.pos
+	float := false
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '.' || c == 'e' || c == 'E' || (c == '+' || c == '-') && float {
+			float = true
+		} else if c < '0' || c > '9' {
+			break
+		}
+		p.pos++
+	}
+	s := p.src[start:p.pos]
+	if float {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			p.fail("invalid number %q", s)
+		}
+		return f
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		p.fail("invalid number %q", s)
+	}
+	return i
+}
+
+func (p *gqlParser) string() string {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		p.pos += 3
+		end := strings.Index(p.src[p.pos:], `"""`)
+		if end < 0 {
+			p.fail("unterminated string")
+		}
+		s := p.src[p.pos : p.pos+end]
+		p.pos += end + 3
+		return strings.Replace(s, `\"""`, `"""`, -1)
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.fail("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			return b.String()
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+			continue
+		}
+		p.pos++
+		if p.pos >= len(p.src) {
+			p.fail("unterminated string")
+		}
+		switch e := p.src[p.pos]; e {
+		case '"', '\\', '/':
+			b.WriteByte(e)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if p.pos+5 > len(p.src) {
+				p.fail("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(p.src[p.pos+1:p.pos+5], 16, 32)
+			if err != nil {
+				p.fail("invalid unicode escape")
+			}
+			b.WriteRune(rune(r))
+			p.pos += 4
+		default:
+			p.fail("invalid escape %q", e)
+		}
+		p.pos++
+	}
+}
+
+// resolveVariables replaces variables in value with their values.
+func resolveVariables(value interface{}, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariable:
+		return variables[string(v)]
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = resolveVariables(v[i], variables)
+		}
+		return out
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key := range v {
+			out[key] = resolveVariables(v[key], variables)
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,487 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// gqlObject is an object of the GraphQL introspection, like __Schema or
+// __Type, with the names of its fields as keys and the name of its type in
+// "__typename". Named types are referred to by the same object, so objects
+// can be cyclic and are only marshaled through the selections.
+type gqlObject map[string]interface{}
+
+// gqlSchema builds the schema of the models resolved by the handlers of the
+// controller.
+type gqlSchema struct {
+	ctrl  fiberGraphQLCtrl
+	types map[string]gqlObject
+}
+
+// Arguments of the list fields, see setArguments().
+var gqlListArguments = []struct{ name, typ string }{
+	{"page", "Int"}, {"per", "Int"}, {"sort", "String"}, {"order", "Order"},
+	{"query", "String"}, {"status", "String"}, {"filter", "JSON"},
+	{"pagination", "String"}, {"after", "String"}, {"before", "String"},
+}
+
+// introspect resolves __schema and __type fields of the query.
+func (ctrl fiberGraphQLCtrl) introspect(field gqlField, vars map[string]interface{}) (interface{}, error) {
+	s := ctrl.schema()
+	for name := range field.arguments {
+		if field.name == "__schema" || name != "name" {
+			return nil, graphQLFieldError(fmt.Sprintf("Unknown argument %q.", name))
+		}
+	}
+	if field.name == "__schema" {
+		return s.selectFields(s.object(), field)
+	}
+	name, ok := resolveVariables(field.arguments["name"], vars).(string)
+	if !ok {
+		return nil, graphQLFieldError("Argument \"name\" of type \"String!\" is required.")
+	}
+	t, ok := s.types[name]
+	if !ok {
+		return nil, nil
+	}
+	return s.selectFields(t, field)
+}
+
+// schema returns the schema of the root fields of the handlers. Fields of
+// the models are their struct fields in the key case, without write-only
+// fields, and their relations. Create and update inputs contain the
+// permitted params.
+func (ctrl fiberGraphQLCtrl) schema() *gqlSchema {
+	s := &gqlSchema{ctrl: ctrl, types: map[string]gqlObject{}}
+	for _, name := range []string{"Int", "Float", "String", "Boolean", "ID"} {
+		s.named("SCALAR", name)
+	}
+	s.named("SCALAR", "JSON")["description"] = "Any JSON value."
+	order := s.named("ENUM", "Order")
+	order["enumValues"] = []interface{}{gqlEnumValue("asc"), gqlEnumValue("desc")}
+	s.addIntrospectionTypes()
+
+	var models []string
+	for key := range ctrl.handlers {
+		if model := strings.SplitN(key, ".", 2)[0]; findParam(models, model) == "" {
+			models = append(models, model)
+		}
+	}
+	sort.Strings(models)
+	var queries, mutations []interface{}
+	for _, model := range models {
+		id := gqlInputValue("id", gqlNonNull(s.types["ID"]))
+		if ctrl.handlers[model+".List"] != nil {
+			var args []interface{}
+			for _, arg := range gqlListArguments {
+				args = append(args, gqlInputValue(arg.name, s.types[arg.typ]))
+			}
+			queries = append(queries, gqlFieldDef(lowerFirst(pluralize(model)), s.listType(model), args...))
+		}
+		if ctrl.handlers[model+".Show"] != nil {
+			queries = append(queries, gqlFieldDef(lowerFirst(model), s.modelType(model), id))
+		}
+		for _, action := range []string{"Create", "Update", "Destroy"} {
+			if ctrl.handlers[model+"."+action] == nil {
+				continue
+			}
+			var args []interface{}
+			if action != "Create" {
+				args = append(args, id)
+			}
+			if action != "Destroy" {
+				args = append(args, gqlInputValue("input", gqlNonNull(s.inputType(model, action))))
+			}
+			mutations = append(mutations, gqlFieldDef(strings.ToLower(action)+model, s.modelType(model), args...))
+		}
+	}
+	s.objectType("Query", queries...)
+	if len(mutations) > 0 {
+		s.objectType("Mutation", mutations...)
+	}
+	return s
+}
+
+// object returns the __Schema object.
+func (s *gqlSchema) object() gqlObject {
+	var names []string
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	types := make([]interface{}, len(names))
+	for i, name := range names {
+		types[i] = s.types[name]
+	}
+	var mutation interface{}
+	if t, ok := s.types["Mutation"]; ok {
+		mutation = t
+	}
+	return gqlObject{
+		"__typename":       "__Schema",
+		"description":      nil,
+		"types":            types,
+		"queryType":        s.types["Query"],
+		"mutationType":     mutation,
+		"subscriptionType": nil,
+		"directives":       []interface{}{},
+	}
+}
+
+// named returns the named type of kind, which is created if it does not
+// exist.
+func (s *gqlSchema) named(kind, name string) gqlObject {
+	if t, ok := s.types[name]; ok {
+		return t
+	}
+	t := gqlObject{
+		"__typename":     "__Type",
+		"kind":           kind,
+		"name":           name,
+		"description":    nil,
+		"specifiedByURL": nil,
+		"fields":         nil,
+		"interfaces":     nil,
+		"possibleTypes":  nil,
+		"enumValues":     nil,
+		"inputFields":    nil,
+		"ofType":         nil,
+		"isOneOf":        nil,
+	}
+	if kind == "INPUT_OBJECT" {
+		t["isOneOf"] = false
+	}
+	s.types[name] = t
+	return t
+}
+
+// objectType returns object type of name with fields.
+func (s *gqlSchema) objectType(name string, fields ...interface{}) gqlObject {
+	t := s.named("OBJECT", name)
+	if fields == nil {
+		fields = []interface{}{}
+	}
+	t["fields"] = fields
+	t["interfaces"] = []interface{}{}
+	return t
+}
+
+// modelType returns object type of model, or JSON if there is no model of
+// the name.
+func (s *gqlSchema) modelType(model string) gqlObject {
+	if t, ok := s.types[model]; ok {
+		return t
+	}
+	m := s.ctrl.backend.ModelByName(model)
+	if m == nil {
+		return s.types["JSON"]
+	}
+	t := s.objectType(model) // added before the fields for cyclic relations
+	record := m.New().Interface()
+	writeOnly := writeOnlyFields(record)
+	kc := s.ctrl.backend.keyCase
+	fields := []interface{}{}
+	var add func(typ reflect.Type)
+	add = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				add(f.Type)
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.PkgPath != "" || name == "-" || findParam(writeOnly, f.Name) != "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fields = append(fields, gqlFieldDef(kc.fromGo(name), s.goType(f.Type)))
+		}
+	}
+	add(reflect.TypeOf(record).Elem())
+	if r, ok := record.(HasRelations); ok {
+		relations := r.Relations()
+		var names []string
+		for name := range relations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fields = append(fields, gqlFieldDef(kc.fromGo(name), gqlList(s.modelType(relations[name].Model))))
+		}
+	}
+	t["fields"] = fields
+	return t
+}
+
+// listType returns object type of the list field of model.
+func (s *gqlSchema) listType(model string) gqlObject {
+	kc := s.ctrl.backend.keyCase
+	return s.objectType(model+"List",
+		gqlFieldDef(kc.fromGo("Records"), gqlList(s.modelType(model))),
+		gqlFieldDef(kc.fromGo("Pagination"), s.types["JSON"]),
+	)
+}
+
+// inputType returns input object type of the permitted params of the
+// action of model, like PostCreateInput.
+func (s *gqlSchema) inputType(model, action string) gqlObject {
+	t := s.named("INPUT_OBJECT", model+action+"Input")
+	m := s.ctrl.backend.ModelByName(model)
+	if m == nil {
+		t["inputFields"] = []interface{}{}
+		return t
+	}
+	typ := reflect.TypeOf(m.New().Interface()).Elem()
+	kc := s.ctrl.backend.keyCase
+	fields := []interface{}{}
+	for _, param := range modelParams(model, m, strings.ToLower(action)) {
+		if f, ok := typ.FieldByName(param); ok {
+			fields = append(fields, gqlInputValue(kc.fromGo(param), s.goType(f.Type)))
+		}
+	}
+	t["inputFields"] = fields
+	return t
+}
+
+// goType returns type of the values of Go type typ in JSON.
+func (s *gqlSchema) goType(typ reflect.Type) gqlObject {
+	switch {
+	case typ == timeType, typ == bcryptPasswordType:
+		return s.types["String"]
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return s.goType(typ.Elem())
+	case reflect.Bool:
+		return s.types["Boolean"]
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return s.types["Int"]
+	case reflect.Float32, reflect.Float64:
+		return s.types["Float"]
+	case reflect.String:
+		return s.types["String"]
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return s.types["String"]
+		}
+		return gqlList(s.goType(typ.Elem()))
+	}
+	return s.types["JSON"]
+}
+
+// addIntrospectionTypes adds the types of the introspection.
+func (s *gqlSchema) addIntrospectionTypes() {
+	str, boolean := s.types["String"], s.types["Boolean"]
+	typ, kind, location := s.named("OBJECT", "__Type"), s.named("ENUM", "__TypeKind"), s.named("ENUM", "__DirectiveLocation")
+	field, inputValue, enumValue := s.named("OBJECT", "__Field"), s.named("OBJECT", "__InputValue"), s.named("OBJECT", "__EnumValue")
+	directive := s.named("OBJECT", "__Directive")
+	includeDeprecated := gqlInputValue("includeDeprecated", boolean)
+	includeDeprecated["defaultValue"] = "false"
+	list := func(t gqlObject) gqlObject {
+		return gqlNonNull(gqlList(gqlNonNull(t)))
+	}
+	enum := func(t gqlObject, names ...string) {
+		values := make([]interface{}, len(names))
+		for i, name := range names {
+			values[i] = gqlEnumValue(name)
+		}
+		t["enumValues"] = values
+	}
+
+	s.objectType("__Schema",
+		gqlFieldDef("description", str),
+		gqlFieldDef("types", list(typ)),
+		gqlFieldDef("queryType", gqlNonNull(typ)),
+		gqlFieldDef("mutationType", typ),
+		gqlFieldDef("subscriptionType", typ),
+		gqlFieldDef("directives", list(directive)),
+	)
+	s.objectType("__Type",
+		gqlFieldDef("kind", gqlNonNull(kind)),
+		gqlFieldDef("name", str),
+		gqlFieldDef("description", str),
+		gqlFieldDef("specifiedByURL", str),
+		gqlFieldDef("fields", gqlList(gqlNonNull(field)), includeDeprecated),
+		gqlFieldDef("interfaces", gqlList(gqlNonNull(typ))),
+		gqlFieldDef("possibleTypes", gqlList(gqlNonNull(typ))),
+		gqlFieldDef("enumValues", gqlList(gqlNonNull(enumValue)), includeDeprecated),
+		gqlFieldDef("inputFields", gqlList(gqlNonNull(inputValue)), includeDeprecated),
+		gqlFieldDef("ofType", typ),
+		gqlFieldDef("isOneOf", boolean),
+	)
+	s.objectType("__Field",
+		gqlFieldDef("name", gqlNonNull(str)),
+		gqlFieldDef("description", str),
+		gqlFieldDef("args", list(inputValue), includeDeprecated),
+		gqlFieldDef("type", gqlNonNull(typ)),
+		gqlFieldDef("isDeprecated", gqlNonNull(boolean)),
+		gqlFieldDef("deprecationReason", str),
+	)
+	s.objectType("__InputValue",
+		gqlFieldDef("name", gqlNonNull(str)),
+		gqlFieldDef("description", str),
+		gqlFieldDef("type", gqlNonNull(typ)),
+		gqlFieldDef("defaultValue", str),
+		gqlFieldDef("isDeprecated", gqlNonNull(boolean)),
+		gqlFieldDef("deprecationReason", str),
+	)
+	s.objectType("__EnumValue",
+		gqlFieldDef("name", gqlNonNull(str)),
+		gqlFieldDef("description", str),
+		gqlFieldDef("isDeprecated", gqlNonNull(boolean)),
+		gqlFieldDef("deprecationReason", str),
+	)
+	s.objectType("__Directive",
+		gqlFieldDef("name", gqlNonNull(str)),
+		gqlFieldDef("description", str),
+		gqlFieldDef("locations", list(location)),
+		gqlFieldDef("args", list(inputValue), includeDeprecated),
+		gqlFieldDef("isRepeatable", gqlNonNull(boolean)),
+	)
+	enum(kind, "SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL")
+	enum(location, "QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION",
+		"FRAGMENT_SPREAD", "INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR",
+		"OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM",
+		"ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION")
+}
+
+// selectFields returns the selected fields of the introspection value of
+// field. Fields are checked against the fields of the type of the object.
+func (s *gqlSchema) selectFields(value interface{}, field gqlField) (interface{}, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			var err error
+			if out[i], err = s.selectFields(v[i], field); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case gqlObject:
+		typeName, _ := v["__typename"].(string)
+		if len(field.selections) == 0 {
+			return nil, graphQLFieldError(fmt.Sprintf("Field %q of type %q must have a selection of subfields.", field.name, typeName))
+		}
+		var obj jsonObject
+		for _, sel := range field.selections {
+			var fieldValue interface{}
+			if sel.name == "__typename" {
+				fieldValue = typeName
+			} else {
+				if err := s.checkField(sel, s.types[typeName]); err != nil {
+					return nil, err
+				}
+				var err error
+				if fieldValue, err = s.selectFields(v[sel.name], sel); err != nil {
+					return nil, err
+				}
+			}
+			b, err := json.Marshal(fieldValue)
+			if err != nil {
+				return nil, err
+			}
+			obj.keys = append(obj.keys, sel.key())
+			obj.values = append(obj.values, b)
+		}
+		return obj, nil
+	}
+	if len(field.selections) > 0 && value != nil {
+		return nil, graphQLFieldError(fmt.Sprintf("Field %q must not have a selection.", field.name))
+	}
+	return value, nil
+}
+
+// checkField returns error if field is not a field of type parent or has
+// arguments the field does not accept.
+func (s *gqlSchema) checkField(field gqlField, parent gqlObject) error {
+	var def gqlObject
+	fields, _ := parent["fields"].([]interface{})
+	for _, f := range fields {
+		if f.(gqlObject)["name"] == field.name {
+			def = f.(gqlObject)
+		}
+	}
+	if def == nil {
+		return graphQLFieldError(fmt.Sprintf("Cannot query field %q on type %q.", field.name, parent["name"]))
+	}
+	args, _ := def["args"].([]interface{})
+	for name := range field.arguments {
+		found := false
+		for _, arg := range args {
+			found = found || arg.(gqlObject)["name"] == name
+		}
+		if !found {
+			return graphQLFieldError(fmt.Sprintf("Unknown argument %q.", name))
+		}
+	}
+	return nil
+}
+
+func gqlFieldDef(name string, typ gqlObject, args ...interface{}) gqlObject {
+	if args == nil {
+		args = []interface{}{}
+	}
+	return gqlObject{
+		"__typename":        "__Field",
+		"name":              name,
+		"description":       nil,
+		"args":              args,
+		"type":              typ,
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}
+}
+
+func gqlInputValue(name string, typ gqlObject) gqlObject {
+	return gqlObject{
+		"__typename":        "__InputValue",
+		"name":              name,
+		"description":       nil,
+		"type":              typ,
+		"defaultValue":      nil,
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}
+}
+
+func gqlEnumValue(name string) gqlObject {
+	return gqlObject{
+		"__typename":        "__EnumValue",
+		"name":              name,
+		"description":       nil,
+		"isDeprecated":      false,
+		"deprecationReason": nil,
+	}
+}
+
+func gqlNonNull(typ gqlObject) gqlObject {
+	return gqlWrapper("NON_NULL", typ)
+}
+
+func gqlList(typ gqlObject) gqlObject {
+	return gqlWrapper("LIST", typ)
+}
+
+func gqlWrapper(kind string, typ gqlObject) gqlObject {
+	return gqlObject{
+		"__typename":     "__Type",
+		"kind":           kind,
+		"name":           nil,
+		"description":    nil,
+		"specifiedByURL": nil,
+		"fields":         nil,
+		"interfaces":     nil,
+		"possibleTypes":  nil,
+		"enumValues":     nil,
+		"inputFields":    nil,
+		"ofType":         typ,
+		"isOneOf":        nil,
+	}
+}
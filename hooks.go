@@ -0,0 +1,292 @@
+package backend
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gopsql/psql"
+)
+
+// Lifecycle hooks can be implemented by models. All hooks except AfterCommit
+// are called in the transaction of the write, returning error from a hook
+// rolls back the transaction. Fields changed by BeforeValidate, BeforeCreate
+// and BeforeUpdate are saved together with the other changes.
+type (
+	HasBeforeValidate interface {
+		BeforeValidate(tx *psql.Tx) error
+	}
+
+	HasBeforeCreate interface {
+		BeforeCreate(tx *psql.Tx) error
+	}
+
+	HasAfterCreate interface {
+		AfterCreate(tx *psql.Tx) error
+	}
+
+	HasBeforeUpdate interface {
+		BeforeUpdate(tx *psql.Tx) error
+	}
+
+	HasAfterUpdate interface {
+		AfterUpdate(tx *psql.Tx) error
+	}
+
+	HasBeforeDestroy interface {
+		BeforeDestroy(tx *psql.Tx) error
+	}
+
+	HasAfterDestroy interface {
+		AfterDestroy(tx *psql.Tx) error
+	}
+
+	// HasAfterCommit is called after the transaction started by
+	// Transaction() in which the record is created, updated or destroyed
+	// is committed.
+	HasAfterCommit interface {
+		AfterCommit()
+	}
+)
+
+// Records to call AfterCommit on, for each of the transactions started by
+// Transaction().
+var afterCommits = struct {
+	sync.Mutex
+	records map[*psql.Tx][]HasAfterCommit
+}{records: map[*psql.Tx][]HasAfterCommit{}}
+
+// Transaction is like the Transaction() of model m, but calls AfterCommit
+// of the records created, updated or destroyed in the transaction with
+// CreateInTransaction(), UpdateInTransaction() or DestroyInTransaction()
+// after the transaction is committed.
+func (backend Backend) Transaction(m *psql.Model, block func(tx *psql.Tx) error) error {
+	var tx *psql.Tx
+	err := m.Transaction(func(t *psql.Tx) error {
+		tx = t
+		afterCommits.Lock()
+		afterCommits.records[tx] = []HasAfterCommit{}
+		afterCommits.Unlock()
+		return block(tx)
+	})
+	afterCommits.Lock()
+	records := afterCommits.records[tx]
+	delete(afterCommits.records, tx)
+	afterCommits.Unlock()
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		record.AfterCommit()
+	}
+	return nil
+}
+
+// CreateInTransaction calls BeforeValidate, validates record, calls
+// BeforeCreate, inserts record of model m with changes, reloads the record
+// and calls AfterCreate.
+func (backend Backend) CreateInTransaction(tx *psql.Tx, m *psql.Model, record interface{}, changes []interface{}) error {
+	more, err := hookChanges(m, record, func() error { return callHook(tx, record, "BeforeValidate") })
+	if err != nil {
+		return err
+	}
+	changes = append(changes, more...)
+	if err := backend.ValidateStruct(record); err != nil {
+		return err
+	}
+	more, err = hookChanges(m, record, func() error { return callHook(tx, record, "BeforeCreate") })
+	if err != nil {
+		return err
+	}
+	changes = append(changes, more...)
+	var id int
+	if err := m.Insert(changes...).Returning(m.ToColumnName("Id")).QueryRowInTransaction(tx, &id); err != nil {
+		return err
+	}
+	if err := m.Find().WHERE("Id", "=", id).QueryInTransaction(tx, record); err != nil {
+		return err
+	}
+	if err := callHook(tx, record, "AfterCreate"); err != nil {
+		return err
+	}
+	onCommit(tx, record)
+	return nil
+}
+
+// UpdateInTransaction calls BeforeValidate and validates record, which is
+// the current record with changes assigned (see AssignUpdate()). If there
+// are changes, it calls BeforeUpdate, saves the changes, reloads the record
+// and calls AfterUpdate.
+//
+// If the model has Version field, the version is incremented, and the
+// record is updated only if its version is still the same as the one of
+// current, otherwise PreconditionFailedError is returned with the latest
+// record. Models without Version field are updated without this check, as
+// the precision of UpdatedAt stored in the database can differ from the one
+// of the loaded record.
+func (backend Backend) UpdateInTransaction(tx *psql.Tx, m *psql.Model, current, record interface{}, changes []interface{}) error {
+	more, err := hookChanges(m, record, func() error { return callHook(tx, record, "BeforeValidate") })
+	if err != nil {
+		return err
+	}
+	changes = append(changes, more...)
+	if err := backend.ValidateStruct(record); err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	more, err = hookChanges(m, record, func() error { return callHook(tx, record, "BeforeUpdate") })
+	if err != nil {
+		return err
+	}
+	changes = append(changes, more...)
+
+	cv := reflect.Indirect(reflect.ValueOf(current))
+	id := fieldInterface(cv, "Id")
+	where := []interface{}{"Id", "=", id}
+	if version := fieldInterface(cv, "Version"); version != nil {
+		if v, ok := toInt64(version); ok {
+			changes = append(changes, "Version", v+1)
+			where = append(where, "Version", "=", v)
+		}
+	}
+	var updated int
+	err = m.Update(changes...).WHERE(where...).Returning(m.ToColumnName("Id")).QueryRowInTransaction(tx, &updated)
+	if backend.IsErrNoRows(err) {
+		latest := m.New().Interface()
+		if err := m.Find().WHERE("Id", "=", id).QueryInTransaction(tx, latest); err != nil {
+			return err
+		}
+		return PreconditionFailedError{serialize(latest, "show"), ETag(latest)}
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.Find().WHERE("Id", "=", id).QueryInTransaction(tx, record); err != nil {
+		return err
+	}
+	if err := callHook(tx, record, "AfterUpdate"); err != nil {
+		return err
+	}
+	onCommit(tx, record)
+	return nil
+}
+
+// DestroyInTransaction calls BeforeDestroy, deletes record of model m and
+// calls AfterDestroy. If the model has DeletedAt field and permanently is
+// false, the record is soft deleted and reloaded, otherwise the record and
+// its dependents (see HasDependents) are deleted permanently.
+func (backend Backend) DestroyInTransaction(tx *psql.Tx, m *psql.Model, record interface{}, permanently bool) error {
+	if err := callHook(tx, record, "BeforeDestroy"); err != nil {
+		return err
+	}
+	id := fieldInterface(reflect.Indirect(reflect.ValueOf(record)), "Id")
+	if _, soft := reflect.TypeOf(m.New().Interface()).Elem().FieldByName("DeletedAt"); soft && !permanently {
+		err := m.Update("DeletedAt", time.Now().UTC().Truncate(time.Second)).
+			WHERE("Id", "=", id).Returning(m.ToColumnName("Id")).QueryRowInTransaction(tx, new(int))
+		if err != nil {
+			return err
+		}
+		if err := m.Find().WHERE("Id", "=", id).QueryInTransaction(tx, record); err != nil {
+			return err
+		}
+	} else {
+		if err := backend.deleteInTransaction(tx, m, id); err != nil {
+			return err
+		}
+	}
+	if err := callHook(tx, record, "AfterDestroy"); err != nil {
+		return err
+	}
+	onCommit(tx, record)
+	return nil
+}
+
+// callHook calls the hook of record by name if record implements it.
+func callHook(tx *psql.Tx, record interface{}, name string) error {
+	switch name {
+	case "BeforeValidate":
+		if h, ok := record.(HasBeforeValidate); ok {
+			return h.BeforeValidate(tx)
+		}
+	case "BeforeCreate":
+		if h, ok := record.(HasBeforeCreate); ok {
+			return h.BeforeCreate(tx)
+		}
+	case "AfterCreate":
+		if h, ok := record.(HasAfterCreate); ok {
+			return h.AfterCreate(tx)
+		}
+	case "BeforeUpdate":
+		if h, ok := record.(HasBeforeUpdate); ok {
+			return h.BeforeUpdate(tx)
+		}
+	case "AfterUpdate":
+		if h, ok := record.(HasAfterUpdate); ok {
+			return h.AfterUpdate(tx)
+		}
+	case "BeforeDestroy":
+		if h, ok := record.(HasBeforeDestroy); ok {
+			return h.BeforeDestroy(tx)
+		}
+	case "AfterDestroy":
+		if h, ok := record.(HasAfterDestroy); ok {
+			return h.AfterDestroy(tx)
+		}
+	}
+	return nil
+}
+
+// hookChanges calls hook and returns changes of the fields of record
+// modified by the hook.
+func hookChanges(m *psql.Model, record interface{}, hook func() error) (changes []interface{}, err error) {
+	rv := reflect.ValueOf(record).Elem()
+	before := reflect.New(rv.Type()).Elem()
+	before.Set(rv)
+	if err = hook(); err != nil {
+		return
+	}
+	var fields []interface{}
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.PkgPath != "" || f.Anonymous || f.Name == "Id" {
+			continue
+		}
+		if !reflect.DeepEqual(rv.Field(i).Interface(), before.Field(i).Interface()) {
+			fields = append(fields, f.Name, rv.Field(i).Interface())
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+	return m.Assign(record, fields...)
+}
+
+// onCommit adds record to the records to call AfterCommit on after tx is
+// committed, if tx is started by Transaction().
+func onCommit(tx *psql.Tx, record interface{}) {
+	r, ok := record.(HasAfterCommit)
+	if !ok {
+		return
+	}
+	afterCommits.Lock()
+	defer afterCommits.Unlock()
+	if records, ok := afterCommits.records[tx]; ok {
+		afterCommits.records[tx] = append(records, r)
+	}
+}
+
+// RestoreInTransaction restores soft deleted record of model m by clearing
+// its DeletedAt field with UpdateInTransaction().
+func (backend Backend) RestoreInTransaction(tx *psql.Tx, m *psql.Model, record interface{}) error {
+	rv := reflect.ValueOf(record).Elem()
+	f := rv.FieldByName("DeletedAt")
+	if !f.IsValid() || !f.CanSet() {
+		return nil
+	}
+	current := m.New().Interface()
+	reflect.ValueOf(current).Elem().Set(rv)
+	f.Set(reflect.Zero(f.Type()))
+	return backend.UpdateInTransaction(tx, m, current, record, []interface{}{"DeletedAt", nil})
+}
@@ -0,0 +1,330 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type (
+	// HTTPParams returns value of route parameter by name of the request,
+	// for example chi.URLParam of github.com/go-chi/chi/v5.
+	HTTPParams func(r *http.Request, key string) string
+
+	// httpCtx implements FiberCtx over http.ResponseWriter and
+	// *http.Request. Status and body are written when the handler returns,
+	// so that headers can be set in any order like in fiber.
+	httpCtx struct {
+		w      http.ResponseWriter
+		r      *http.Request
+		params HTTPParams
+		next   func() error
+
+		body     []byte
+		bodyRead bool
+
+		status     int
+		stream     io.Reader
+		responded  bool
+		nextCalled bool
+	}
+
+	httpLocalsKey struct{}
+)
+
+// NewHTTPHandler returns http.Handler running handlers written for FiberCtx,
+// like the controllers and their middlewares, with net/http. Calling Next()
+// of FiberCtx runs the next handler. Params returns route parameters and can
+// be nil if there are no parameters. Errors returned by the handlers or
+// panics of errors are responded with HandleError(). Responses are
+// negotiated with FiberNegotiate().
+func (backend *Backend) NewHTTPHandler(params HTTPParams, handlers ...FiberHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := newHTTPCtx(w, r, params)
+		i := 0
+		c.next = func() error {
+			if i++; i < len(handlers) {
+				return backend.FiberNegotiate(handlers[i])(c)
+			}
+			return nil
+		}
+		if len(handlers) > 0 {
+			backend.serveHTTPCtx(c, backend.FiberNegotiate(handlers[0]))
+		}
+	})
+}
+
+// NewHTTPMiddleware returns net/http middleware (like the ones of chi)
+// running handler written for FiberCtx, for example Authenticate of the
+// sessions controller. Calling Next() of FiberCtx calls the next
+// http.Handler. Locals are kept in the context of the request, so they are
+// available in FiberCtx of the next handlers.
+func (backend *Backend) NewHTTPMiddleware(params HTTPParams, handler FiberHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := newHTTPCtx(w, r, params)
+			c.next = func() error {
+				next.ServeHTTP(c.w, c.r)
+				return nil
+			}
+			backend.serveHTTPCtx(c, backend.FiberNegotiate(handler))
+		})
+	}
+}
+
+func newHTTPCtx(w http.ResponseWriter, r *http.Request, params HTTPParams) *httpCtx {
+	if _, ok := r.Context().Value(httpLocalsKey{}).(map[interface{}]interface{}); !ok {
+		r = r.WithContext(context.WithValue(r.Context(), httpLocalsKey{}, map[interface{}]interface{}{}))
+	}
+	return &httpCtx{w: w, r: r, params: params}
+}
+
+// serveHTTPCtx runs handler and writes the response.
+func (backend *Backend) serveHTTPCtx(c *httpCtx, handler FiberHandler) {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if e, ok := r.(error); ok {
+					err = e
+				} else {
+					err = fmt.Errorf("%v", r)
+				}
+			}
+		}()
+		return handler(c)
+	}()
+	if err != nil {
+		status, content := backend.HandleError(err)
+		c.stream = nil
+		c.SendStatus(status)
+		c.JSON(content)
+	}
+	if c.nextCalled && !c.responded {
+		return // response is written by the next handler
+	}
+	if c.status == 0 {
+		c.status = 200
+	}
+	c.w.WriteHeader(c.status)
+	if c.stream != nil {
+		io.Copy(c.w, c.stream)
+		if closer, ok := c.stream.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func (c *httpCtx) Body() []byte {
+	if !c.bodyRead {
+		c.bodyRead = true
+		if c.r.Body != nil {
+			c.body, _ = ioutil.ReadAll(c.r.Body)
+		}
+	}
+	return c.body
+}
+
+// BodyParser parses JSON or form-urlencoded request body into out.
+func (c *httpCtx) BodyParser(out interface{}) error {
+	ctype, _, _ := mime.ParseMediaType(c.r.Header.Get("Content-Type"))
+	switch {
+	case strings.HasSuffix(ctype, "json"):
+		return json.Unmarshal(c.Body(), out)
+	case ctype == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(c.Body()))
+		if err != nil {
+			return err
+		}
+		return decodeValues(out, values, "form")
+	}
+	return errors.New("unsupported content type: " + ctype)
+}
+
+func (c *httpCtx) Get(key string, defaultValue ...string) string {
+	if strings.EqualFold(key, "Host") {
+		return valueOrDefault(c.r.Host, defaultValue)
+	}
+	return valueOrDefault(c.r.Header.Get(key), defaultValue)
+}
+
+func (c *httpCtx) IP() string {
+	host, _, err := net.SplitHostPort(c.r.RemoteAddr)
+	if err != nil {
+		return c.r.RemoteAddr
+	}
+	return host
+}
+
+func (c *httpCtx) JSON(data interface{}, ctype ...string) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	contentType := "application/json"
+	if len(ctype) > 0 {
+		contentType = ctype[0]
+	}
+	c.Set("Content-Type", contentType)
+	c.stream = bytes.NewReader(b)
+	c.responded = true
+	return nil
+}
+
+func (c *httpCtx) Locals(key interface{}, value ...interface{}) interface{} {
+	locals := c.r.Context().Value(httpLocalsKey{}).(map[interface{}]interface{})
+	if len(value) > 0 {
+		locals[key] = value[0]
+		return value[0]
+	}
+	return locals[key]
+}
+
+func (c *httpCtx) Method(override ...string) string {
+	if len(override) > 0 {
+		c.r.Method = override[0]
+	}
+	return c.r.Method
+}
+
+func (c *httpCtx) Next() error {
+	c.nextCalled = true
+	if c.next == nil {
+		return nil
+	}
+	return c.next()
+}
+
+func (c *httpCtx) Params(key string, defaultValue ...string) string {
+	var value string
+	if c.params != nil {
+		value = c.params(c.r, key)
+	}
+	return valueOrDefault(value, defaultValue)
+}
+
+// Protocol returns https for TLS requests or the X-Forwarded-Proto header
+// set by the proxy, otherwise http.
+func (c *httpCtx) Protocol() string {
+	if c.r.TLS != nil {
+		return "https"
+	}
+	if proto := c.r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		return proto
+	}
+	return "http"
+}
+
+func (c *httpCtx) Queries() map[string]string {
+	queries := map[string]string{}
+	for key, values := range c.r.URL.Query() {
+		if len(values) > 0 {
+			queries[key] = values[0]
+		}
+	}
+	return queries
+}
+
+func (c *httpCtx) Query(key string, defaultValue ...string) string {
+	return valueOrDefault(c.r.URL.Query().Get(key), defaultValue)
+}
+
+func (c *httpCtx) QueryParser(out interface{}) error {
+	return decodeValues(out, c.r.URL.Query(), "query")
+}
+
+// SendStatus sets status of the response, and the status text as body if
+// body is not set, like fiber.
+func (c *httpCtx) SendStatus(status int) error {
+	c.status = status
+	c.responded = true
+	if c.stream == nil && status != 204 && status != 304 {
+		c.stream = strings.NewReader(http.StatusText(status))
+	}
+	return nil
+}
+
+func (c *httpCtx) SendStream(stream io.Reader, size ...int) error {
+	if len(size) > 0 && size[0] >= 0 {
+		c.Set("Content-Length", strconv.Itoa(size[0]))
+	}
+	c.stream = stream
+	c.responded = true
+	return nil
+}
+
+func (c *httpCtx) Set(key string, val string) {
+	c.w.Header().Set(key, val)
+}
+
+func valueOrDefault(value string, defaultValue []string) string {
+	if value == "" && len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return value
+}
+
+// decodeValues sets fields of struct pointed by out to values. Keys are
+// names in tag or field names, case-insensitive. Fields of embedded structs
+// are also set.
+func decodeValues(out interface{}, values url.Values, tag string) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("out must be pointer to struct")
+	}
+	return decodeStruct(rv.Elem(), values, tag)
+}
+
+func decodeStruct(rv reflect.Value, values url.Values, tag string) error {
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := decodeStruct(rv.Field(i), values, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		var vs []string
+		for key, v := range values {
+			if strings.EqualFold(key, name) {
+				vs = v
+				break
+			}
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		value, err := formValue(f.Type, vs)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, rv.Field(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gopsql/psql"
+)
+
+// DefaultIdempotencyPeriod is the default period of replaying responses of
+// the requests with Idempotency-Key header, see SetIdempotencyPeriod().
+const DefaultIdempotencyPeriod = 24 * time.Hour
+
+// DefaultIdempotencyLockTimeout is the default timeout of the requests with
+// Idempotency-Key header in progress, see SetIdempotencyLockTimeout().
+const DefaultIdempotencyLockTimeout = 5 * time.Minute
+
+// Maximum length of the Idempotency-Key header.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyCtx is FiberCtx capturing the status, the headers and the JSON
+// response of the handler of a request with Idempotency-Key header.
+type idempotencyCtx struct {
+	FiberCtx
+	status   int
+	headers  map[string]string
+	response []byte
+	streamed bool
+}
+
+// FiberIdempotency returns handler storing the response of handler for the
+// Idempotency-Key header of the request, if IdempotencyKey model is added
+// (see AddModelIdempotencyKey()) and the request is authenticated. Keys are
+// scoped to the admin. Retries with the same key and the same request (name
+// of the route, id param, queries and body) replay the stored status,
+// headers and response with Idempotent-Replayed header, without calling
+// handler. Reusing the key for a different request or while the first
+// request is in progress results in InputErrors. Keys are released if
+// handler fails or streams the response, or if the request is still in
+// progress after the lock timeout (see SetIdempotencyLockTimeout()), so the
+// request can be retried, and expire after the idempotency period (see
+// SetIdempotencyPeriod() and DeleteExpiredIdempotencyKeys()). MountFiber() uses it for the POST routes
+// that need authentication.
+func (backend *Backend) FiberIdempotency(name string, handler FiberHandler) FiberHandler {
+	return func(c FiberCtx) (err error) {
+		key := c.Get("Idempotency-Key")
+		m := backend.ModelByName(getName(c, "IdempotencyKey"))
+		adminId, _, ok := backend.FiberGetAdminAndSessionId(c)
+		if key == "" || m == nil || !ok {
+			return handler(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return NewInputErrors("Idempotency-Key", "invalid")
+		}
+		m = m.Quiet()
+		hash := idempotencyRequestHash(c, name)
+
+		var ik IdempotencyKey
+		err = m.Find().WHERE("AdminId", "=", adminId, "Key", "=", key).Query(&ik)
+		if err == nil && backend.idempotencyKeyExpired(ik) {
+			if err = m.Delete().WHERE("Id", "=", ik.Id).Execute(); err != nil {
+				return
+			}
+			err = backend.dbConn.ErrNoRows()
+		}
+		if backend.IsErrNoRows(err) {
+			now := time.Now().UTC()
+			err = m.Insert(
+				"AdminId", adminId,
+				"Key", key,
+				"RequestHash", hash,
+				"LockedAt", now,
+				"CreatedAt", now,
+				"UpdatedAt", now,
+			).Returning(m.ToColumnName("Id")).QueryRow(&ik.Id)
+			if err == nil {
+				return backend.runIdempotent(c, m, ik.Id, handler)
+			}
+			// may be inserted by concurrent request with the same key
+			if m.Find().WHERE("AdminId", "=", adminId, "Key", "=", key).Query(&ik) != nil {
+				return
+			}
+		} else if err != nil {
+			return
+		}
+
+		if ik.RequestHash != hash {
+			return NewInputErrors("Idempotency-Key", "conflict")
+		}
+		if ik.Status == 0 {
+			return NewInputErrors("Idempotency-Key", "processing")
+		}
+		var headers map[string]string
+		json.Unmarshal([]byte(ik.Headers), &headers)
+		for key, value := range headers {
+			c.Set(key, value)
+		}
+		c.Set("Idempotent-Replayed", "true")
+		c.SendStatus(ik.Status)
+		return c.JSON(json.RawMessage(ik.Response))
+	}
+}
+
+// runIdempotent runs handler and stores its response in the idempotency key
+// of id, or deletes the key if handler fails.
+func (backend *Backend) runIdempotent(c FiberCtx, m *psql.Model, id int, handler FiberHandler) (err error) {
+	ic := &idempotencyCtx{FiberCtx: c, headers: map[string]string{}}
+	stored := false
+	defer func() {
+		if !stored {
+			m.Delete().WHERE("Id", "=", id).Execute()
+		}
+	}()
+	if err = handler(ic); err != nil || ic.streamed || ic.response == nil {
+		return
+	}
+	if ic.status == 0 {
+		ic.status = 200
+	}
+	headers, _ := json.Marshal(ic.headers)
+	err = m.Update(
+		"Status", ic.status,
+		"Headers", string(headers),
+		"Response", string(ic.response),
+		"LockedAt", nil,
+		"UpdatedAt", time.Now().UTC(),
+	).WHERE("Id", "=", id).Execute()
+	stored = err == nil
+	return
+}
+
+// idempotencyKeyExpired returns true if ik was created before the
+// idempotency period, or is locked by a request in progress for longer than
+// the lock timeout.
+func (backend *Backend) idempotencyKeyExpired(ik IdempotencyKey) bool {
+	now := time.Now()
+	return ik.CreatedAt.Before(now.Add(-backend.idempotencyPeriod)) ||
+		ik.Status == 0 && ik.LockedAt != nil && ik.LockedAt.Before(now.Add(-backend.idempotencyLock))
+}
+
+// DeleteExpiredIdempotencyKeys deletes idempotency keys created before the
+// idempotency period, and the ones locked for longer than the lock timeout,
+// see FiberIdempotency(). Keys of all added models of IdempotencyKey type
+// are deleted, as the name of the model used by FiberIdempotency() can be
+// set in the locals of fiber context. Purge() calls it.
+func (backend Backend) DeleteExpiredIdempotencyKeys() error {
+	now := time.Now().UTC()
+	for _, m := range backend.models {
+		if _, ok := m.New().Interface().(*IdempotencyKey); !ok {
+			continue
+		}
+		err := m.Delete().Where(fmt.Sprintf("%s < $1 OR (%s = 0 AND %s < $2)",
+			m.ToColumnName("CreatedAt"), m.ToColumnName("Status"), m.ToColumnName("LockedAt")),
+			now.Add(-backend.idempotencyPeriod), now.Add(-backend.idempotencyLock)).Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// idempotencyRequestHash returns SHA-256 hash of the name of the route, the
+// id param, the sorted queries and the body of the request. Multipart bodies
+// are hashed by their fields instead, since the boundary of the parts
+// changes every time the form is sent.
+func idempotencyRequestHash(c FiberCtx, name string) string {
+	queries := c.Queries()
+	keys := make([]string, 0, len(queries))
+	for key := range queries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", name, c.Params("id"))
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\n", key, queries[key])
+	}
+	body := c.Body()
+	if ctype, params, _ := mime.ParseMediaType(c.Get("Content-Type")); ctype == "multipart/form-data" {
+		if fields, err := multipartFields(body, params["boundary"]); err == nil {
+			body = fields
+		}
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// multipartFields returns the sorted fields of multipart body, including the
+// names and the contents of the files.
+func multipartFields(body []byte, boundary string) ([]byte, error) {
+	mf, err := multipart.NewReader(bytes.NewReader(body), boundary).ReadForm(maxFormMemory)
+	if err != nil {
+		return nil, err
+	}
+	defer mf.RemoveAll()
+	var buf bytes.Buffer
+	keys := make([]string, 0, len(mf.Value))
+	for key := range mf.Value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range mf.Value[key] {
+			fmt.Fprintf(&buf, "%q=%q\n", key, value)
+		}
+	}
+	keys = keys[:0]
+	for key := range mf.File {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, fh := range mf.File[key] {
+			f, err := fh.Open()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&buf, "%q=@%q:", key, fh.Filename)
+			_, err = io.Copy(&buf, f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *idempotencyCtx) JSON(data interface{}, ctype ...string) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	c.response = b
+	return c.FiberCtx.JSON(data, ctype...)
+}
+
+func (c *idempotencyCtx) SendStatus(status int) error {
+	c.status = status
+	return c.FiberCtx.SendStatus(status)
+}
+
+func (c *idempotencyCtx) SendStream(stream io.Reader, size ...int) error {
+	c.streamed = true
+	return c.FiberCtx.SendStream(stream, size...)
+}
+
+// Set sets response header, the ones other than Content-Type and
+// Content-Length are replayed.
+func (c *idempotencyCtx) Set(key string, val string) {
+	switch strings.ToLower(key) {
+	case "content-type", "content-length":
+	default:
+		c.headers[key] = val
+	}
+	c.FiberCtx.Set(key, val)
+}
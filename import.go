@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gopsql/psql"
+)
+
+// ImportResult contains IDs of inserted rows, or serialized records in dry
+// run, and errors of invalid rows.
+type ImportResult struct {
+	Ids     []int
+	Records []interface{}
+	Errors  InputErrorsWithIndex
+}
+
+// FiberImport parses the request body of a fiber context as JSON array of
+// objects, or as CSV if Content-Type is text/csv or the format query is csv,
+// and inserts rows into model m. CSV header contains field names or column
+// names, only the permitted params are used. Every row is validated, invalid
+// rows are reported with their indexes (zero-based, not counting the CSV
+// header) and all valid rows are inserted in one transaction with
+// CreateInTransaction(). Values of the fields with uniqueness validation must
+// also be unique within the rows, compared like the validation does (see
+// HasUniqueKey), duplicates are reported as "unique" errors, as are unique
+// index violations while inserting. If the dry_run query is true, nothing is
+// inserted and the valid rows are returned as records serialized with the
+// "show" view, without calling the hooks. Rows are inserted into the tenant of the
+// request, see FiberTenantConditions().
+func (backend Backend) FiberImport(c FiberCtx, m *psql.Model, params []string) (result ImportResult, err error) {
+	var rows []json.RawMessage
+	if c.Query("format") == "csv" || strings.HasPrefix(c.Get("Content-Type"), "text/csv") {
+		rows, err = csvToJSONRows(m, params, c.Body())
+	} else {
+		err = json.Unmarshal(c.Body(), &rows)
+	}
+	if err != nil {
+		err = NewInputErrors("Body", "invalid")
+		return
+	}
+
+	var records, valid []interface{}
+	var changes [][]interface{}
+	var indexes []int
+	unique := uniqueFields(reflect.TypeOf(m.New().Interface()).Elem())
+	seen := map[string]map[interface{}]bool{}
+	for i, row := range rows {
+		record := m.New().Interface()
+		rowChanges, e := m.Assign(record, m.Permit(params...).Filter([]byte(row)), m.CreatedAt(), m.UpdatedAt())
+		if e != nil {
+			result.Errors = append(result.Errors, InputErrorWithIndex{NewInputError("Body", "invalid"), i})
+			continue
+		}
+		tenant, e := backend.fiberTenantChanges(c, m, record)
+		if e != nil {
+			err = e
+			return
+		}
+		rowChanges = append(rowChanges, tenant...)
+		if e := backend.ValidateStruct(record); e != nil {
+			ierrs, ok := backend.inputErrorsWithIndex(e, i)
+			if !ok {
+				err = e
+				return
+			}
+			result.Errors = append(result.Errors, ierrs...)
+			continue
+		}
+		var duplicates InputErrorsWithIndex
+		for _, field := range unique {
+			if seen[field][uniqueKey(record, field)] {
+				duplicates = append(duplicates, InputErrorWithIndex{NewInputError(field, "unique"), i})
+			}
+		}
+		if len(duplicates) > 0 {
+			result.Errors = append(result.Errors, duplicates...)
+			continue
+		}
+		for _, field := range unique {
+			if seen[field] == nil {
+				seen[field] = map[interface{}]bool{}
+			}
+			seen[field][uniqueKey(record, field)] = true
+		}
+		records = append(records, serialize(record, "show"))
+		valid = append(valid, record)
+		changes = append(changes, rowChanges)
+		indexes = append(indexes, i)
+	}
+
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		result.Records = records
+		return
+	}
+	if len(changes) == 0 {
+		return
+	}
+	var ids []int
+	err = backend.Transaction(m, func(tx *psql.Tx) error {
+		for i, rowChanges := range changes {
+			if err := backend.CreateInTransaction(tx, m, valid[i], rowChanges); err != nil {
+				if isUniqueViolation(err) {
+					return uniqueViolationWithIndex(m, err, indexes[i])
+				}
+				return err
+			}
+			if err := backend.fiberAddTenantMemberInTransaction(c, tx, m, valid[i]); err != nil {
+				return err
+			}
+			id, _ := toInt64(fieldInterface(reflect.ValueOf(valid[i]).Elem(), "Id"))
+			ids = append(ids, int(id))
+		}
+		return nil
+	})
+	if err == nil {
+		result.Ids = ids
+	}
+	return
+}
+
+// csvToJSONRows converts CSV rows to JSON objects. Header is mapped to
+// permitted field names and values are converted to the types of the
+// fields. Columns not permitted are ignored.
+func csvToJSONRows(m *psql.Model, params []string, body []byte) (rows []json.RawMessage, err error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	header, err := r.Read()
+	if err != nil {
+		return
+	}
+	typ := reflect.TypeOf(m.New().Interface()).Elem()
+	fields := make([]reflect.StructField, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		for _, param := range params {
+			if strings.EqualFold(name, param) || name == m.ToColumnName(param) {
+				fields[i], _ = typ.FieldByName(param)
+				break
+			}
+		}
+	}
+	for {
+		var record []string
+		record, err = r.Read()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		row := map[string]interface{}{}
+		for i, value := range record {
+			if i >= len(fields) || fields[i].Name == "" {
+				continue
+			}
+			v, e := parseStringValue(fields[i].Type, value)
+			if e != nil {
+				v = value // reported as invalid row when assigning
+			}
+			row[fields[i].Name] = v
+		}
+		b, _ := json.Marshal(row)
+		rows = append(rows, b)
+	}
+}
+
+// uniqueFields returns names of the fields with uniqueness validation.
+func uniqueFields(typ reflect.Type) (fields []string) {
+	for i := 0; i < typ.NumField(); i++ {
+		for _, tag := range strings.Split(typ.Field(i).Tag.Get("validate"), ",") {
+			if tag == "uniqueness" {
+				fields = append(fields, typ.Field(i).Name)
+			}
+		}
+	}
+	return
+}
+
+// uniqueKey returns the value of field of record compared by the uniqueness
+// validation, see HasUniqueKey.
+func uniqueKey(record interface{}, field string) interface{} {
+	if r, ok := record.(HasUniqueKey); ok {
+		return r.UniqueKey(field)
+	}
+	return fieldInterface(reflect.ValueOf(record).Elem(), field)
+}
+
+// isUniqueViolation returns true if err is a unique index violation of
+// PostgreSQL or SQLite.
+func isUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "duplicate key value violates unique constraint") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
+}
+
+// uniqueViolationField returns the unique field whose column is mentioned in
+// the unique index violation error, or the first unique field, or "Body" if
+// the model has no unique fields.
+func uniqueViolationField(m *psql.Model, unique []string, err error) string {
+	for _, field := range unique {
+		if strings.Contains(err.Error(), m.ToColumnName(field)) {
+			return field
+		}
+	}
+	if len(unique) > 0 {
+		return unique[0]
+	}
+	return "Body"
+}
@@ -0,0 +1,263 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// KeyCase is the naming strategy of the keys of JSON inputs and outputs,
+// see SetKeyCase().
+type KeyCase int
+
+const (
+	// Go field names like CreatedAt, the default.
+	KeyCaseGo KeyCase = iota
+
+	// Camel case like createdAt.
+	KeyCaseCamel
+
+	// Snake case like created_at.
+	KeyCaseSnake
+)
+
+type (
+	// casedJSON is value marshaled with its keys converted to the key case.
+	casedJSON struct {
+		value   interface{}
+		keyCase KeyCase
+	}
+
+	// keepKeys is value whose keys are not converted to the key case, like
+	// the OpenAPI document, JSON Schemas and the GraphQL responses.
+	keepKeys struct {
+		value interface{}
+	}
+)
+
+func (v casedJSON) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(v.value)
+	if err != nil {
+		return nil, err
+	}
+	return convertJSONKeys(b, v.keyCase.fromGo, 0)
+}
+
+func (v keepKeys) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.value)
+}
+
+// fromGo converts Go field name to the key case.
+func (kc KeyCase) fromGo(name string) string {
+	if kc == KeyCaseGo {
+		return name
+	}
+	words := splitWords(name)
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if kc == KeyCaseCamel && i > 0 {
+			word = upperFirst(word)
+		}
+		words[i] = word
+	}
+	if kc == KeyCaseSnake {
+		return strings.Join(words, "_")
+	}
+	return strings.Join(words, "")
+}
+
+// toGo converts name in the key case to Go field name. Acronyms are not
+// restored, for example both url_path and urlPath become UrlPath.
+func (kc KeyCase) toGo(name string) string {
+	if kc == KeyCaseGo {
+		return name
+	}
+	words := splitWords(name)
+	for i, word := range words {
+		words[i] = upperFirst(strings.ToLower(word))
+	}
+	return strings.Join(words, "")
+}
+
+// inputError converts Name and FullName of e to the key case. Only parts
+// that are Go field names are converted, column names of filters and names
+// of query params are kept.
+func (kc KeyCase) inputError(e InputError) InputError {
+	convert := func(name string) string {
+		if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+			return name
+		}
+		return kc.fromGo(name)
+	}
+	parts := strings.Split(e.FullName, ".")
+	for i := range parts {
+		parts[i] = convert(parts[i])
+	}
+	e.FullName = strings.Join(parts, ".")
+	e.Name = convert(e.Name)
+	return e
+}
+
+// convertQuery converts query param in the key case: values of sort and the
+// fields of filters to column names, values of fields and include to Go
+// field names.
+func (kc KeyCase) convertQuery(key, value string) (string, string) {
+	if kc == KeyCaseGo {
+		return key, value
+	}
+	switch key {
+	case "sort":
+		return key, KeyCaseSnake.fromGo(value)
+	case "fields", "include":
+		items := strings.Split(value, ",")
+		for i := range items {
+			items[i] = kc.toGo(strings.TrimSpace(items[i]))
+		}
+		return key, strings.Join(items, ",")
+	}
+	if m := filterKeyRegexp.FindStringSubmatch(key); m != nil {
+		key = "filter[" + KeyCaseSnake.fromGo(m[1]) + "]"
+		if m[2] != "" {
+			key += "[" + m[2] + "]"
+		}
+	}
+	return key, value
+}
+
+// splitWords splits name into words by underscores, hyphens, spaces and
+// case changes, for example URLPath into URL and Path.
+func splitWords(name string) (words []string) {
+	runes := []rune(name)
+	start := 0
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// convertJSONKeys converts keys of all objects of JSON value data with
+// convert, keeping the order of the keys.
+func convertJSONKeys(data []byte, convert func(string) string, depth int) ([]byte, error) {
+	var out bytes.Buffer
+	if err := writeConvertedJSON(&out, data, convert, depth); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeConvertedJSON(out *bytes.Buffer, data []byte, convert func(string) string, depth int) error {
+	if depth > binaryMaxDepth {
+		return errors.New("json: max depth exceeded")
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return errors.New("json: unexpected end of input")
+	}
+	switch data[0] {
+	case '{':
+		keys, values, err := jsonObjectFields(data)
+		if err != nil {
+			return err
+		}
+		out.WriteByte('{')
+		for i := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			key, _ := json.Marshal(convert(keys[i]))
+			out.Write(key)
+			out.WriteByte(':')
+			if err := writeConvertedJSON(out, values[i], convert, depth+1); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		out.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeConvertedJSON(out, item, convert, depth+1); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	default:
+		out.Write(data)
+	}
+	return nil
+}
+
+// convertSchemaKeys converts names of the properties and the required
+// properties of the JSON Schemas in v with convert.
+func convertSchemaKeys(v interface{}, convert func(string) string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range v {
+			switch value := value.(type) {
+			case map[string]interface{}:
+				if key == "properties" {
+					props := map[string]interface{}{}
+					for name, prop := range value {
+						props[convert(name)] = convertSchemaKeys(prop, convert)
+					}
+					out[key] = props
+					continue
+				}
+			case []string:
+				if key == "required" {
+					required := make([]string, len(value))
+					for i, name := range value {
+						required[i] = convert(name)
+					}
+					out[key] = required
+					continue
+				}
+			}
+			out[key] = convertSchemaKeys(value, convert)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = convertSchemaKeys(v[i], convert)
+		}
+		return out
+	}
+	return v
+}
@@ -2,6 +2,7 @@ package backend
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -14,7 +15,7 @@ type (
 	Admin struct {
 		Id        int
 		Name      string          `validate:"gt=0,lte=30,uniqueness"`
-		Password  bcrypt.Password `validate:"required"`
+		Password  bcrypt.Password `validate:"required" backend:"writeonly"`
 		CreatedAt time.Time
 		UpdatedAt time.Time
 		DeletedAt *time.Time
@@ -31,6 +32,44 @@ type (
 		UpdatedAt time.Time
 	}
 
+	// Idempotency key sent by admin in the Idempotency-Key header, with
+	// hash of the request and the response to replay. Status is zero and
+	// LockedAt is the start of the request while the request is in
+	// progress. See FiberIdempotency().
+	IdempotencyKey struct {
+		Id          int
+		AdminId     int
+		Key         string
+		RequestHash string
+		Status      int
+		Headers     string
+		Response    string
+		LockedAt    *time.Time
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+
+	// Tenant partitions admins and records of the models with TenantId
+	// field. Subdomain identifies the tenant in the host name and the
+	// X-Tenant header. See AddModelTenant().
+	Tenant struct {
+		Id        int
+		Name      string `validate:"gt=0,lte=100"`
+		Subdomain string `validate:"gt=0,lte=63,uniqueness"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
+	// Membership of admin in tenant. Admins without memberships are not
+	// limited to any tenant.
+	TenantMembership struct {
+		Id        int
+		TenantId  int
+		AdminId   int
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
 	IsAdmin interface {
 		GetId() int
 		GetName() string
@@ -50,10 +89,61 @@ type (
 		Serialize(typ string, data ...interface{}) interface{}
 	}
 
+	// HasWriteOnlyFields returns names of the fields that can be assigned
+	// but are never included in responses, in addition to the fields with
+	// the backend:"writeonly" tag and the bcrypt.Password fields.
+	HasWriteOnlyFields interface {
+		WriteOnlyFields() []string
+	}
+
 	HasParams interface {
 		Params(string) []string
 	}
 
+	// HasDependents returns names of models that depend on this model and
+	// their foreign key fields. Dependents, and their dependents, are
+	// deleted when the record is deleted permanently, together with
+	// records of registered models with foreign key field named after the
+	// model, like AdminId for Admin.
+	HasDependents interface {
+		Dependents() map[string]string
+	}
+
+	// HasRelations returns relations that can be included in the
+	// responses with the include query, see FiberSerialize().
+	HasRelations interface {
+		Relations() map[string]Relation
+	}
+
+	// Relation refers to the records of Model whose ForeignKey field
+	// equals to the Id of the record.
+	Relation struct {
+		Model      string
+		ForeignKey string
+	}
+
+	// Filterable returns struct field names and the operators allowed
+	// for each of them in the filter query of list endpoints. Fields are
+	// referred to by column names in the query string.
+	Filterable interface {
+		Filters() map[string][]string
+	}
+
+	// Searchable returns struct field names of the text fields matched by
+	// the search query of list endpoints. See SearchSchema() for the
+	// indexes of the fields.
+	Searchable interface {
+		SearchFields() []string
+	}
+
+	// HasUniqueKey returns the value of field compared by the uniqueness
+	// validation of the model, normalized like its IsUnique method does,
+	// for example lowercased. Rows of an import with the same keys are
+	// duplicates, see FiberImport().
+	HasUniqueKey interface {
+		UniqueKey(field string) interface{}
+	}
+
 	IsAdminSession interface {
 		GetId() int
 		GetAdminId() int
@@ -90,12 +180,18 @@ func (a *Admin) SetUpdatedAt(updatedAt time.Time)  { a.UpdatedAt = updatedAt }
 func (a *Admin) SetDeletedAt(deletedAt *time.Time) { a.DeletedAt = deletedAt }
 
 func (Admin) AfterCreateSchema(m psql.Model) string {
+	var index string
 	if m.Connection().DriverName() == "sqlite" {
-		return fmt.Sprintf("CREATE UNIQUE INDEX unique_admin ON %s (%s COLLATE NOCASE);",
+		index = fmt.Sprintf("CREATE UNIQUE INDEX unique_admin ON %s (%s COLLATE NOCASE);",
+			m.TableName(), m.ToColumnName("Name"))
+	} else {
+		index = fmt.Sprintf("CREATE UNIQUE INDEX unique_admin ON %s USING btree (lower(%s));",
 			m.TableName(), m.ToColumnName("Name"))
 	}
-	return fmt.Sprintf("CREATE UNIQUE INDEX unique_admin ON %s USING btree (lower(%s));",
-		m.TableName(), m.ToColumnName("Name"))
+	if search := SearchSchema(m); search != "" {
+		return index + "\n" + search
+	}
+	return index
 }
 
 func (Admin) DataType(m psql.Model, fieldName string) (dataType string) {
@@ -117,6 +213,47 @@ func (a Admin) IsUnique(backend *Backend, field string) bool { // uniqueness
 	return true
 }
 
+func (a Admin) UniqueKey(field string) interface{} {
+	if field == "Name" {
+		return strings.ToLower(a.Name)
+	}
+	return fieldInterface(reflect.ValueOf(a), field)
+}
+
+var (
+	_ HasDependents = (*Admin)(nil)
+	_ HasRelations  = (*Admin)(nil)
+	_ Filterable    = (*Admin)(nil)
+	_ Searchable    = (*Admin)(nil)
+	_ HasUniqueKey  = (*Admin)(nil)
+)
+
+func (Admin) Dependents() map[string]string {
+	return map[string]string{
+		"AdminSession": "AdminId",
+	}
+}
+
+func (Admin) Relations() map[string]Relation {
+	return map[string]Relation{
+		"Sessions": {"AdminSession", "AdminId"},
+	}
+}
+
+func (Admin) SearchFields() []string {
+	return []string{"Name"}
+}
+
+func (Admin) Filters() map[string][]string {
+	return map[string][]string{
+		"Id":        {"eq", "ne", "in", "nin"},
+		"Name":      {"eq", "ne", "in", "nin", "like"},
+		"CreatedAt": {"gt", "gte", "lt", "lte"},
+		"UpdatedAt": {"gt", "gte", "lt", "lte"},
+		"DeletedAt": {"gt", "gte", "lt", "lte", "null"},
+	}
+}
+
 var (
 	_ Serializable = (*Admin)(nil)
 )
@@ -139,6 +276,16 @@ func (a Admin) Serialize(typ string, data ...interface{}) interface{} {
 	return a
 }
 
+// serialize returns the typ view of i if i is Serializable, otherwise i,
+// with the write-only fields of i and the view removed.
+func serialize(i interface{}, typ string) interface{} {
+	view := i
+	if s, ok := i.(Serializable); ok {
+		view = s.Serialize(typ)
+	}
+	return withoutFields(view, append(writeOnlyFields(i), writeOnlyFields(view)...))
+}
+
 var (
 	_ IsAdminSession = (*AdminSession)(nil)
 )
@@ -158,6 +305,33 @@ func (a *AdminSession) SetUserAgent(userAgent string)    { a.UserAgent = userAge
 func (a *AdminSession) SetCreatedAt(createdAt time.Time) { a.CreatedAt = createdAt }
 func (a *AdminSession) SetUpdatedAt(updatedAt time.Time) { a.UpdatedAt = updatedAt }
 
+var (
+	_ Serializable = (*AdminSession)(nil)
+)
+
+type (
+	adminSessionForList struct {
+		Id        int
+		AdminId   int
+		IpAddress string
+		UserAgent string
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+)
+
+// Serialize hides SessionId, which identifies the session in the token.
+func (a AdminSession) Serialize(typ string, data ...interface{}) interface{} {
+	return adminSessionForList{
+		Id:        a.Id,
+		AdminId:   a.AdminId,
+		IpAddress: a.IpAddress,
+		UserAgent: a.UserAgent,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
 func (AdminSession) AfterCreateSchema(m psql.Model) string {
 	return fmt.Sprintf("CREATE UNIQUE INDEX unique_admin_session ON %s (%s, %s);",
 		m.TableName(), m.ToColumnName("AdminId"), m.ToColumnName("SessionId"))
@@ -173,3 +347,28 @@ func (AdminSession) DataType(m psql.Model, fieldName string) (dataType string) {
 	}
 	return
 }
+
+func (IdempotencyKey) AfterCreateSchema(m psql.Model) string {
+	return fmt.Sprintf("CREATE UNIQUE INDEX unique_idempotency_key ON %s (%s, %s);",
+		m.TableName(), m.ToColumnName("AdminId"), m.ToColumnName("Key"))
+}
+
+var (
+	_ HasDependents = (*Tenant)(nil)
+)
+
+func (Tenant) AfterCreateSchema(m psql.Model) string {
+	return fmt.Sprintf("CREATE UNIQUE INDEX unique_tenant ON %s (%s);",
+		m.TableName(), m.ToColumnName("Subdomain"))
+}
+
+func (Tenant) Dependents() map[string]string {
+	return map[string]string{
+		"TenantMembership": "TenantId",
+	}
+}
+
+func (TenantMembership) AfterCreateSchema(m psql.Model) string {
+	return fmt.Sprintf("CREATE UNIQUE INDEX unique_tenant_membership ON %s (%s, %s);",
+		m.TableName(), m.ToColumnName("TenantId"), m.ToColumnName("AdminId"))
+}
@@ -0,0 +1,298 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// msgpackEncoder writes values in MessagePack, see
+// https://github.com/msgpack/msgpack/blob/master/spec.md.
+type msgpackEncoder struct {
+	bytes.Buffer
+}
+
+func (e *msgpackEncoder) writeNil() {
+	e.WriteByte(0xc0)
+}
+
+func (e *msgpackEncoder) writeBool(b bool) {
+	if b {
+		e.WriteByte(0xc3)
+	} else {
+		e.WriteByte(0xc2)
+	}
+}
+
+func (e *msgpackEncoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.WriteByte(byte(i))
+	case i >= math.MinInt8:
+		e.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16:
+		e.WriteByte(0xd1)
+		e.writeBE(uint64(i), 2)
+	case i >= math.MinInt32:
+		e.WriteByte(0xd2)
+		e.writeBE(uint64(i), 4)
+	default:
+		e.WriteByte(0xd3)
+		e.writeBE(uint64(i), 8)
+	}
+}
+
+func (e *msgpackEncoder) writeUint(u uint64) {
+	switch {
+	case u < 128:
+		e.WriteByte(byte(u))
+	case u <= math.MaxUint8:
+		e.Write([]byte{0xcc, byte(u)})
+	case u <= math.MaxUint16:
+		e.WriteByte(0xcd)
+		e.writeBE(u, 2)
+	case u <= math.MaxUint32:
+		e.WriteByte(0xce)
+		e.writeBE(u, 4)
+	default:
+		e.WriteByte(0xcf)
+		e.writeBE(u, 8)
+	}
+}
+
+func (e *msgpackEncoder) writeFloat(f float64) {
+	e.WriteByte(0xcb)
+	e.writeBE(math.Float64bits(f), 8)
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	e.writeHeader(len(s), 0xa0, 32, 0xd9)
+	e.WriteString(s)
+}
+
+func (e *msgpackEncoder) writeArrayHeader(n int) {
+	e.writeHeader(n, 0x90, 16, 0xdc)
+}
+
+func (e *msgpackEncoder) writeMapHeader(n int) {
+	e.writeHeader(n, 0x80, 16, 0xde)
+}
+
+// writeHeader writes fix type if n is less than fixMax, otherwise the
+// smallest of the types starting from first (8-bit, 16-bit and 32-bit
+// lengths for strings, 16-bit and 32-bit lengths for arrays and maps).
+func (e *msgpackEncoder) writeHeader(n int, fix byte, fixMax int, first byte) {
+	switch {
+	case n < fixMax:
+		e.WriteByte(fix | byte(n))
+	case first == 0xd9 && n <= math.MaxUint8:
+		e.Write([]byte{first, byte(n)})
+	case n <= math.MaxUint16:
+		if first == 0xd9 {
+			first++
+		}
+		e.WriteByte(first)
+		e.writeBE(uint64(n), 2)
+	default:
+		if first == 0xd9 {
+			first++
+		}
+		e.WriteByte(first + 1)
+		e.writeBE(uint64(n), 4)
+	}
+}
+
+func (e *msgpackEncoder) writeBE(u uint64, size int) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u)
+	e.Write(b[8-size:])
+}
+
+// msgpackToJSON converts MessagePack value to JSON. Binary data is converted
+// to base64 string like []byte of encoding/json, timestamps to RFC 3339
+// strings. Other extension types are not supported.
+func msgpackToJSON(data []byte) ([]byte, error) {
+	d := &binaryDecoder{data: data}
+	var out bytes.Buffer
+	if err := d.msgpack(&out, 0); err != nil {
+		return nil, err
+	}
+	if d.pos != len(data) {
+		return nil, errors.New("msgpack: extra data after value")
+	}
+	return out.Bytes(), nil
+}
+
+func (d *binaryDecoder) msgpack(out *bytes.Buffer, depth int) error {
+	if depth > binaryMaxDepth {
+		return errors.New("msgpack: max depth exceeded")
+	}
+	c, err := d.byte()
+	if err != nil {
+		return err
+	}
+	switch {
+	case c < 0x80:
+		out.WriteString(strconv.Itoa(int(c)))
+		return nil
+	case c >= 0xe0:
+		out.WriteString(strconv.Itoa(int(int8(c))))
+		return nil
+	case c&0xf0 == 0x80:
+		return d.msgpackMap(out, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.msgpackArray(out, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.writeString(out, int(c&0x1f))
+	}
+	switch c {
+	case 0xc0:
+		out.WriteString("null")
+	case 0xc2:
+		out.WriteString("false")
+	case 0xc3:
+		out.WriteString("true")
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return err
+		}
+		b, err := d.bytes(n)
+		if err != nil {
+			return err
+		}
+		j, _ := json.Marshal(b)
+		out.Write(j)
+	case 0xca:
+		u, err := d.uint(4)
+		if err != nil {
+			return err
+		}
+		return writeJSONFloat(out, float64(math.Float32frombits(uint32(u))))
+	case 0xcb:
+		u, err := d.uint(8)
+		if err != nil {
+			return err
+		}
+		return writeJSONFloat(out, math.Float64frombits(u))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return err
+		}
+		out.WriteString(strconv.FormatUint(u, 10))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return err
+		}
+		i := int64(u<<(64-8*size)) >> (64 - 8*size) // sign extension
+		out.WriteString(strconv.FormatInt(i, 10))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xc7, 0xc8, 0xc9:
+		var n int
+		if c >= 0xd4 {
+			n = 1 << (c - 0xd4)
+		} else {
+			u, err := d.uint(1 << (c - 0xc7))
+			if err != nil {
+				return err
+			}
+			n = int(u)
+		}
+		typ, err := d.byte()
+		if err != nil {
+			return err
+		}
+		b, err := d.bytes(uint64(n))
+		if err != nil {
+			return err
+		}
+		return writeMsgpackTimestamp(out, int8(typ), b)
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return err
+		}
+		return d.writeString(out, int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return err
+		}
+		return d.msgpackArray(out, int(n), depth)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return err
+		}
+		return d.msgpackMap(out, int(n), depth)
+	default:
+		return errors.New("msgpack: invalid type " + strconv.Itoa(int(c)))
+	}
+	return nil
+}
+
+func (d *binaryDecoder) msgpackArray(out *bytes.Buffer, n, depth int) error {
+	out.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := d.msgpack(out, depth+1); err != nil {
+			return err
+		}
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+func (d *binaryDecoder) msgpackMap(out *bytes.Buffer, n, depth int) error {
+	out.WriteByte('{')
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		var key bytes.Buffer
+		if err := d.msgpack(&key, depth+1); err != nil {
+			return err
+		}
+		if err := writeJSONKey(out, key.Bytes()); err != nil {
+			return err
+		}
+		if err := d.msgpack(out, depth+1); err != nil {
+			return err
+		}
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// writeMsgpackTimestamp writes timestamp extension (type -1) as RFC 3339
+// string.
+func writeMsgpackTimestamp(out *bytes.Buffer, typ int8, b []byte) error {
+	if typ != -1 {
+		return errors.New("msgpack: unsupported extension type " + strconv.Itoa(int(typ)))
+	}
+	var t time.Time
+	switch len(b) {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		t = time.Unix(int64(u&(1<<34-1)), int64(u>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b)))
+	default:
+		return errors.New("msgpack: invalid timestamp")
+	}
+	j, _ := json.Marshal(t.UTC().Format(time.RFC3339Nano))
+	out.Write(j)
+	return nil
+}
@@ -0,0 +1,407 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type (
+	// binaryFormat is a binary encoding negotiated with the Accept and
+	// Content-Type headers. Values are converted from and to JSON, so
+	// MarshalJSON methods, write-only fields and the order of the keys of
+	// objects are kept.
+	binaryFormat struct {
+		contentType string
+		newEncoder  func() binaryEncoder
+		toJSON      func([]byte) ([]byte, error)
+	}
+
+	binaryEncoder interface {
+		writeNil()
+		writeBool(bool)
+		writeInt(int64)
+		writeUint(uint64)
+		writeFloat(float64)
+		writeString(string)
+		writeArrayHeader(int)
+		writeMapHeader(int)
+		Bytes() []byte
+	}
+
+	binaryDecoder struct {
+		data []byte
+		pos  int
+	}
+
+	// negotiatedCtx is FiberCtx responding JSON in the format of the Accept
+	// header and converting request body in the format of the Content-Type
+	// header to JSON. Keys of the JSON inputs and outputs and the query
+	// params are converted from and to the key case.
+	negotiatedCtx struct {
+		FiberCtx
+		format    string // content type of the response, empty for JSON
+		keyCase   KeyCase
+		raw       []byte // request body converted to JSON
+		body      []byte // raw with keys converted to Go field names
+		converted bool
+	}
+)
+
+const binaryMaxDepth = 1000
+
+var (
+	msgpackFormat = binaryFormat{"application/msgpack",
+		func() binaryEncoder { return &msgpackEncoder{} }, msgpackToJSON}
+	cborFormat = binaryFormat{"application/cbor",
+		func() binaryEncoder { return &cborEncoder{} }, cborToJSON}
+
+	binaryFormats = map[string]binaryFormat{
+		"application/msgpack":     msgpackFormat,
+		"application/x-msgpack":   msgpackFormat,
+		"application/vnd.msgpack": msgpackFormat,
+		"application/cbor":        cborFormat,
+	}
+)
+
+// FiberNegotiate returns handler responding in JSON, MessagePack or CBOR
+// according to the Accept header, and accepting request body in MessagePack
+// (Content-Type: application/msgpack) or CBOR (application/cbor) by
+// converting it to JSON, so Body() and BodyParser() of the controllers work
+// the same. Keys are converted from and to the key case (see SetKeyCase()).
+// Errors and panics of errors of handler are responded with
+// FiberHandleError() if the response is not in JSON, otherwise they are
+// returned as usual. Routes registered by MountFiber() and the net/http
+// adapters are negotiated.
+func (backend *Backend) FiberNegotiate(handler FiberHandler) FiberHandler {
+	return func(c FiberCtx) error {
+		nc := backend.negotiate(c)
+		err := nc.convertBody()
+		if err == nil {
+			if nc.format == "" {
+				return handler(nc)
+			}
+			err = func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						if e, ok := r.(error); ok {
+							err = e
+						} else {
+							err = fmt.Errorf("%v", r)
+						}
+					}
+				}()
+				return handler(nc)
+			}()
+		}
+		if err != nil && nc.format != "" {
+			return backend.FiberHandleError(nc, err)
+		}
+		return err
+	}
+}
+
+// FiberHandleError responds err with the status and content of
+// HandleError() in the format of the Accept header, for example in the
+// ErrorHandler of fiber:
+//
+//	fiber.New(fiber.Config{
+//		ErrorHandler: func(c *fiber.Ctx, err error) error {
+//			return backend.Default.FiberHandleError(c, err)
+//		},
+//	})
+func (backend Backend) FiberHandleError(c FiberCtx, err error) error {
+	status, content := backend.HandleError(err)
+	c.SendStatus(status)
+	return backend.negotiate(c).JSON(content)
+}
+
+// negotiate returns negotiatedCtx of c.
+func (backend Backend) negotiate(c FiberCtx) *negotiatedCtx {
+	if nc, ok := c.(*negotiatedCtx); ok {
+		return nc
+	}
+	return &negotiatedCtx{
+		FiberCtx: c,
+		format:   negotiateFormat(c.Get("Accept")),
+		keyCase:  backend.keyCase,
+	}
+}
+
+// negotiateFormat returns content type of the binary format of the highest
+// quality in the Accept header, or empty string for JSON, which is preferred
+// for wildcards and equal qualities.
+func negotiateFormat(accept string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		var format string
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+		default:
+			f, ok := binaryFormats[mediaType]
+			if !ok {
+				continue
+			}
+			format = f.contentType
+		}
+		if q > 0 && q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best
+}
+
+// convertBody converts request body in binary format to JSON, and keys of
+// JSON body in the key case to Go field names.
+func (c *negotiatedCtx) convertBody() error {
+	if c.converted {
+		return nil
+	}
+	ctype, _, _ := mime.ParseMediaType(c.FiberCtx.Get("Content-Type"))
+	f, isBinary := binaryFormats[ctype]
+	isJSON := ctype == "application/json" || strings.HasSuffix(ctype, "+json")
+	if !isBinary && (!isJSON || c.keyCase == KeyCaseGo) {
+		return nil
+	}
+	body := c.FiberCtx.Body()
+	if isBinary && len(body) > 0 {
+		b, err := f.toJSON(body)
+		if err != nil {
+			return NewInputErrors("Body", "invalid")
+		}
+		body = b
+	}
+	c.raw, c.body, c.converted = body, body, true
+	if c.keyCase != KeyCaseGo && len(body) > 0 {
+		if b, err := convertJSONKeys(body, c.keyCase.toGo, 0); err == nil {
+			c.body = b
+		}
+	}
+	return nil
+}
+
+func (c *negotiatedCtx) Body() []byte {
+	if c.converted {
+		return c.body
+	}
+	return c.FiberCtx.Body()
+}
+
+// rawBody returns request body in JSON without converting its keys.
+func (c *negotiatedCtx) rawBody() []byte {
+	if c.converted {
+		return c.raw
+	}
+	return c.FiberCtx.Body()
+}
+
+func (c *negotiatedCtx) BodyParser(out interface{}) error {
+	if c.converted {
+		return json.Unmarshal(c.body, out)
+	}
+	return c.FiberCtx.BodyParser(out)
+}
+
+// Get returns application/json as Content-Type if the request body has been
+// converted to JSON.
+func (c *negotiatedCtx) Get(key string, defaultValue ...string) string {
+	if c.converted && strings.EqualFold(key, "Content-Type") {
+		return "application/json"
+	}
+	return c.FiberCtx.Get(key, defaultValue...)
+}
+
+func (c *negotiatedCtx) Queries() map[string]string {
+	queries := c.FiberCtx.Queries()
+	if c.keyCase == KeyCaseGo {
+		return queries
+	}
+	converted := map[string]string{}
+	for key, value := range queries {
+		key, value = c.keyCase.convertQuery(key, value)
+		converted[key] = value
+	}
+	return converted
+}
+
+func (c *negotiatedCtx) Query(key string, defaultValue ...string) string {
+	if c.keyCase == KeyCaseGo {
+		return c.FiberCtx.Query(key, defaultValue...)
+	}
+	return valueOrDefault(c.Queries()[key], defaultValue)
+}
+
+func (c *negotiatedCtx) QueryParser(out interface{}) error {
+	if c.keyCase == KeyCaseGo {
+		return c.FiberCtx.QueryParser(out)
+	}
+	values := url.Values{}
+	for key, value := range c.Queries() {
+		values.Set(key, value)
+	}
+	return decodeValues(out, values, "query")
+}
+
+func (c *negotiatedCtx) JSON(data interface{}, ctype ...string) error {
+	if _, ok := data.(keepKeys); !ok && c.keyCase != KeyCaseGo {
+		data = casedJSON{data, c.keyCase}
+	}
+	if c.format == "" {
+		return c.FiberCtx.JSON(data, ctype...)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	enc := binaryFormats[c.format].newEncoder()
+	if err := transcodeJSON(enc, b, 0); err != nil {
+		return err
+	}
+	c.Set("Content-Type", c.format)
+	out := enc.Bytes()
+	return c.SendStream(bytes.NewReader(out), len(out))
+}
+
+// transcodeJSON writes JSON value data with enc. Integers are written as
+// integers, other numbers as float64.
+func transcodeJSON(enc binaryEncoder, data []byte, depth int) error {
+	if depth > binaryMaxDepth {
+		return errors.New("json: max depth exceeded")
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return errors.New("json: unexpected end of input")
+	}
+	switch data[0] {
+	case '{':
+		keys, values, err := jsonObjectFields(data)
+		if err != nil {
+			return err
+		}
+		enc.writeMapHeader(len(keys))
+		for i := range keys {
+			enc.writeString(keys[i])
+			if err := transcodeJSON(enc, values[i], depth+1); err != nil {
+				return err
+			}
+		}
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		enc.writeArrayHeader(len(items))
+		for _, item := range items {
+			if err := transcodeJSON(enc, item, depth+1); err != nil {
+				return err
+			}
+		}
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		enc.writeString(s)
+	case 't':
+		enc.writeBool(true)
+	case 'f':
+		enc.writeBool(false)
+	case 'n':
+		enc.writeNil()
+	default:
+		s := string(data)
+		if !strings.ContainsAny(s, ".eE") {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				enc.writeInt(i)
+				return nil
+			}
+			if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+				enc.writeUint(u)
+				return nil
+			}
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		enc.writeFloat(f)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errors.New("unexpected end of data")
+	}
+	d.pos++
+	return d.data[d.pos-1], nil
+}
+
+func (d *binaryDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("unexpected end of data")
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+// uint reads big-endian unsigned integer of size bytes.
+func (d *binaryDecoder) uint(size int) (uint64, error) {
+	b, err := d.bytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var buf [8]byte
+	copy(buf[8-size:], b)
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func (d *binaryDecoder) writeString(out *bytes.Buffer, n int) error {
+	b, err := d.bytes(uint64(n))
+	if err != nil {
+		return err
+	}
+	j, _ := json.Marshal(string(b))
+	out.Write(j)
+	return nil
+}
+
+// writeJSONKey writes JSON value key as key of JSON object. Numbers and
+// booleans are converted to strings.
+func writeJSONKey(out *bytes.Buffer, key []byte) error {
+	switch {
+	case len(key) == 0 || key[0] == '{' || key[0] == '[':
+		return errors.New("invalid key of map")
+	case key[0] != '"':
+		key, _ = json.Marshal(string(key))
+	}
+	out.Write(key)
+	out.WriteByte(':')
+	return nil
+}
+
+func writeJSONFloat(out *bytes.Buffer, f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return errors.New("unsupported float value")
+	}
+	out.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	return nil
+}
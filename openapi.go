@@ -0,0 +1,628 @@
+package backend
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type openAPIModel struct {
+	typ       reflect.Type
+	writeOnly []string
+	params    map[string][]string // permitted params of create and update
+}
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	fiberPathParamRe = regexp.MustCompile(`:(\w+)`)
+)
+
+// OpenAPI returns OpenAPI 3 document of the routes registered by MountFiber()
+// with options. Schemas of the models are generated from the types and the
+// validate tags of the fields, request bodies contain the permitted params.
+// Names of the properties are in the key case (see SetKeyCase()).
+func (backend *Backend) OpenAPI(options FiberMountOptions) (map[string]interface{}, error) {
+	routes, err := backend.fiberRoutes(options)
+	if err != nil {
+		return nil, err
+	}
+	models := map[string]openAPIModel{}
+	for _, route := range routes {
+		var names []string
+		switch {
+		case route.model == "Session" && route.action == "Me":
+			names = []string{"Admin"}
+		case route.model == "Admin" && route.action == "Sessions":
+			names = []string{"Admin", "AdminSession"}
+		case !route.html():
+			names = []string{route.model}
+		}
+		for _, name := range names {
+			if _, ok := models[name]; ok {
+				continue
+			}
+			m := backend.ModelByName(name)
+			if m == nil {
+				continue // not a model, like Session and Schema
+			}
+			record := m.New().Interface()
+			model := openAPIModel{
+				typ:       reflect.TypeOf(record).Elem(),
+				writeOnly: writeOnlyFields(record),
+				params:    map[string][]string{},
+			}
+			for _, action := range []string{"create", "update"} {
+				model.params[action] = modelParams(name, m, action)
+			}
+			models[name] = model
+		}
+	}
+	doc := openAPIDocument(options, routes, models)
+	if backend.keyCase != KeyCaseGo {
+		doc = convertSchemaKeys(doc, backend.keyCase.fromGo).(map[string]interface{})
+	}
+	return doc, nil
+}
+
+func openAPIDocument(options FiberMountOptions, routes []fiberRoute, models map[string]openAPIModel) map[string]interface{} {
+	title, version := options.Title, options.Version
+	if title == "" {
+		title = "API"
+	}
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	schemas := map[string]interface{}{
+		"InputError":          openAPIStructSchema(reflect.TypeOf(InputError{}), nil),
+		"InputErrorWithIndex": openAPIStructSchema(reflect.TypeOf(InputErrorWithIndex{}), nil),
+		"Message":             openAPIStructSchema(reflect.TypeOf(struct{ Message string }{}), nil),
+		"Pagination":          map[string]interface{}{"type": "object"},
+		"ModelSchema": openAPIObject(map[string]interface{}{
+			"Name":   map[string]interface{}{"type": "string"},
+			"Schema": map[string]interface{}{"type": "object"},
+			"Params": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		}),
+	}
+	var names []string
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schemas[name] = openAPIStructSchema(models[name].typ, models[name].writeOnly)
+	}
+
+	counts := map[string]int{}
+	for _, route := range routes {
+		counts[route.name]++
+	}
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		if route.model == "" || route.html() {
+			continue
+		}
+		path := fiberPathParamRe.ReplaceAllString(route.path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		op := openAPIOperation(route, models)
+		op["operationId"] = route.name
+		if counts[route.name] > 1 {
+			op["operationId"] = route.name + "." + route.method
+		}
+		item[strings.ToLower(route.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"responses": map[string]interface{}{
+				"InputErrors":          openAPIErrorsResponse("Input errors", "InputError"),
+				"InputErrorsWithIndex": openAPIErrorsResponse("Input errors of items", "InputErrorWithIndex"),
+				"Unauthorized":         openAPIResponse("Not signed in", openAPIRef("Message")),
+				"NotFound":             openAPIResponse("Not found", openAPIRef("Message")),
+			},
+			"securitySchemes": map[string]interface{}{
+				"Authorization": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "Authorization",
+				},
+			},
+		},
+	}
+}
+
+// openAPIOperation returns operation object of route without operationId.
+func openAPIOperation(route fiberRoute, models map[string]openAPIModel) map[string]interface{} {
+	model := route.model
+	op := map[string]interface{}{
+		"tags": []string{model},
+	}
+	params := []interface{}{}
+	for _, match := range fiberPathParamRe.FindAllStringSubmatch(route.path, -1) {
+		schema := map[string]interface{}{"type": "integer"}
+		switch match[1] {
+		case "format":
+			schema = map[string]interface{}{"type": "string", "enum": []string{"csv", "xlsx", "ndjson"}}
+		case "name":
+			schema = map[string]interface{}{"type": "string"}
+		}
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		})
+	}
+	query := func(names ...string) {
+		for _, name := range names {
+			param := map[string]interface{}{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			}
+			switch name {
+			case "page", "per":
+				param["schema"] = map[string]interface{}{"type": "integer"}
+			case "order":
+				param["schema"] = map[string]interface{}{"type": "string", "enum": []string{"asc", "desc"}}
+			case "status":
+				param["schema"] = map[string]interface{}{"type": "string", "enum": []string{"deleted"}}
+			case "pagination":
+				param["schema"] = map[string]interface{}{"type": "string", "enum": []string{"cursor"}}
+			case "dry_run":
+				param["schema"] = map[string]interface{}{"type": "boolean"}
+			case "filter":
+				param["style"] = "deepObject"
+				param["schema"] = map[string]interface{}{"type": "object"}
+			}
+			params = append(params, param)
+		}
+	}
+	ifMatch := func() {
+		params = append(params, map[string]interface{}{
+			"name":   "If-Match",
+			"in":     "header",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	body := func(schema interface{}) {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  openAPIContent(schema),
+		}
+	}
+	formBody := func(schema interface{}) {
+		content := openAPIContent(schema)
+		for _, ctype := range []string{"application/x-www-form-urlencoded", "multipart/form-data"} {
+			content[ctype] = map[string]interface{}{"schema": schema}
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  content,
+		}
+	}
+	responses := map[string]interface{}{}
+	ok := func(schema interface{}) {
+		responses["200"] = openAPIResponse("OK", schema)
+	}
+	noContent := func() {
+		responses["204"] = map[string]interface{}{"description": "No Content"}
+	}
+	errs := func(codes ...string) {
+		for _, code := range codes {
+			switch code {
+			case "400":
+				responses[code] = openAPIRefResponse("InputErrors")
+			case "400i":
+				responses["400"] = openAPIRefResponse("InputErrorsWithIndex")
+			case "404":
+				responses[code] = openAPIRefResponse("NotFound")
+			case "412":
+				responses[code] = openAPIResponse("Precondition Failed", openAPIRef(model))
+			}
+		}
+	}
+	list := func(key string, schema interface{}) map[string]interface{} {
+		return openAPIObject(map[string]interface{}{
+			key:          map[string]interface{}{"type": "array", "items": schema},
+			"Pagination": openAPIRef("Pagination"),
+		})
+	}
+	bulk := func(items interface{}) {
+		properties := map[string]interface{}{
+			"Mode": map[string]interface{}{"type": "string", "enum": []string{BulkTransaction, BulkBestEffort}},
+		}
+		if items == nil {
+			properties["Ids"] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}}
+		} else {
+			properties["Items"] = map[string]interface{}{"type": "array", "items": items}
+		}
+		body(openAPIObject(properties))
+		ok(openAPIObject(map[string]interface{}{
+			"Ids":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			"Errors": map[string]interface{}{"type": "array", "items": openAPIRef("InputErrorWithIndex")},
+		}))
+		errs("400i")
+	}
+
+	switch model + "." + route.action {
+	case "Session.SignIn":
+		body(map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"Name":     map[string]interface{}{"type": "string"},
+				"Password": map[string]interface{}{"type": "string", "format": "password"},
+			},
+			"required": []string{"Name", "Password"},
+		})
+		ok(openAPIObject(map[string]interface{}{"Token": map[string]interface{}{"type": "string"}}))
+		errs("400")
+	case "Session.Me":
+		ok(map[string]interface{}{"allOf": []interface{}{openAPIRef("Admin")}, "nullable": true})
+	case "Session.SignOut":
+		noContent()
+	case "Admin.Sessions":
+		query("page", "per", "sort", "order")
+		schema := list("Sessions", openAPIRef("AdminSession"))
+		schema["properties"].(map[string]interface{})["Current"] = map[string]interface{}{"type": "integer"}
+		ok(schema)
+		errs("404")
+	case "Admin.RevokeSessions", "Admin.RevokeSession":
+		noContent()
+		errs("404")
+	case "Schema.List":
+		ok(openAPIObject(map[string]interface{}{
+			"Schemas": map[string]interface{}{"type": "array", "items": openAPIRef("ModelSchema")},
+		}))
+	case "Schema.Show":
+		ok(openAPIRef("ModelSchema"))
+		errs("404")
+	case "GraphQL.Execute":
+		request := map[string]interface{}{
+			"query":         map[string]interface{}{"type": "string"},
+			"variables":     map[string]interface{}{"type": "object"},
+			"operationName": map[string]interface{}{"type": "string"},
+		}
+		if route.method == "GET" {
+			query("query", "variables", "operationName")
+		} else {
+			schema := openAPIObject(request)
+			schema["required"] = []string{"query"}
+			body(schema)
+		}
+		ok(openAPIObject(map[string]interface{}{
+			"data": map[string]interface{}{"type": "object", "nullable": true},
+			"errors": map[string]interface{}{"type": "array", "items": openAPIObject(map[string]interface{}{
+				"message":    map[string]interface{}{"type": "string"},
+				"path":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"extensions": map[string]interface{}{"type": "object"},
+			})},
+		}))
+		errs("400")
+	default:
+		switch route.action {
+		case "List":
+			query("page", "per", "sort", "order", "query", "status", "filter", "fields", "include",
+				"pagination", "after", "before")
+			if model == "Admin" {
+				schema := list("Admins", openAPIRef(model))
+				schema["properties"].(map[string]interface{})["SessionsCount"] = map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": map[string]interface{}{"type": "integer"},
+				}
+				ok(schema)
+			} else {
+				ok(list("Records", openAPIRef(model)))
+			}
+			errs("400")
+		case "Export":
+			query("sort", "order", "query", "status", "filter")
+			binary := map[string]interface{}{"type": "string", "format": "binary"}
+			responses["200"] = map[string]interface{}{
+				"description": "OK",
+				"content": map[string]interface{}{
+					"text/csv": map[string]interface{}{"schema": binary},
+					"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": map[string]interface{}{"schema": binary},
+					"application/x-ndjson": map[string]interface{}{"schema": binary},
+				},
+			}
+			errs("400")
+		case "Import":
+			query("format", "dry_run")
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{
+						"type":  "array",
+						"items": openAPIParamsSchema(models[model], "create"),
+					}},
+					"text/csv": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+				},
+			}
+			ok(openAPIObject(map[string]interface{}{
+				"Ids":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+				"Records": map[string]interface{}{"type": "array", "items": openAPIRef(model)},
+				"Errors":  map[string]interface{}{"type": "array", "items": openAPIRef("InputErrorWithIndex")},
+			}))
+			errs("400i")
+		case "BulkUpdate":
+			item := openAPIParamsSchema(models[model], "update")
+			item["properties"].(map[string]interface{})["Id"] = map[string]interface{}{"type": "integer"}
+			item["required"] = []string{"Id"}
+			bulk(item)
+		case "BulkDestroy", "BulkRestore":
+			bulk(nil)
+		case "Show":
+			query("fields", "include")
+			ok(openAPIRef(model))
+			errs("400", "404")
+		case "Create":
+			formBody(openAPIParamsSchema(models[model], "create"))
+			ok(openAPIRef(model))
+			errs("400")
+		case "Update":
+			ifMatch()
+			formBody(openAPIParamsSchema(models[model], "update"))
+			ok(openAPIRef(model))
+			errs("400", "404", "412")
+		case "Destroy":
+			ifMatch()
+			ok(openAPIRef(model))
+			errs("404", "412")
+		case "Purge":
+			ifMatch()
+			noContent()
+			errs("400", "404", "412")
+		case "Restore":
+			ok(openAPIRef(model))
+			errs("404", "412")
+		}
+	}
+
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if !route.public {
+		op["security"] = []interface{}{map[string]interface{}{"Authorization": []string{}}}
+		responses["401"] = openAPIRefResponse("Unauthorized")
+	}
+	op["responses"] = responses
+	return op
+}
+
+// openAPIStructSchema returns schema of struct type typ. Fields are marked as
+// writeOnly if they are in writeOnly.
+func openAPIStructSchema(typ reflect.Type, writeOnly []string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	var add func(typ reflect.Type)
+	add = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				add(f.Type)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			schema := openAPITypeSchema(f.Type)
+			for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+				key, value := rule, ""
+				if i := strings.Index(rule, "="); i > -1 {
+					key, value = rule[:i], rule[i+1:]
+				}
+				if key == "required" {
+					required = append(required, f.Name)
+					continue
+				}
+				openAPIValidateRule(schema, key, value)
+			}
+			for _, name := range writeOnly {
+				if name == f.Name {
+					schema["writeOnly"] = true
+				}
+			}
+			properties[f.Name] = schema
+		}
+	}
+	add(typ)
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPITypeSchema returns schema of Go type typ.
+func openAPITypeSchema(typ reflect.Type) map[string]interface{} {
+	switch {
+	case typ == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case typ == bcryptPasswordType:
+		return map[string]interface{}{"type": "string", "format": "password"}
+	}
+	switch typ.Kind() {
+	case reflect.Ptr:
+		schema := openAPITypeSchema(typ.Elem())
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": openAPITypeSchema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPITypeSchema(typ.Elem())}
+	case reflect.Struct:
+		return openAPIStructSchema(typ, nil)
+	}
+	return map[string]interface{}{}
+}
+
+// openAPIValidateRule adds constraint of validate rule (key=value) to
+// schema.
+func openAPIValidateRule(schema map[string]interface{}, key, value string) {
+	n, err := strconv.ParseFloat(value, 64)
+	isNumber := err == nil
+	switch schema["type"] {
+	case "string":
+		if !isNumber {
+			break
+		}
+		switch key {
+		case "gt":
+			schema["minLength"] = int(n) + 1
+		case "gte", "min":
+			schema["minLength"] = int(n)
+		case "lt":
+			schema["maxLength"] = int(n) - 1
+		case "lte", "max":
+			schema["maxLength"] = int(n)
+		case "len":
+			schema["minLength"] = int(n)
+			schema["maxLength"] = int(n)
+		}
+	case "integer", "number":
+		if !isNumber {
+			break
+		}
+		switch key {
+		case "gt":
+			schema["minimum"] = n
+			schema["exclusiveMinimum"] = true
+		case "gte", "min":
+			schema["minimum"] = n
+		case "lt":
+			schema["maximum"] = n
+			schema["exclusiveMaximum"] = true
+		case "lte", "max":
+			schema["maximum"] = n
+		}
+	case "array":
+		if !isNumber {
+			break
+		}
+		switch key {
+		case "gt":
+			schema["minItems"] = int(n) + 1
+		case "gte", "min":
+			schema["minItems"] = int(n)
+		case "lt":
+			schema["maxItems"] = int(n) - 1
+		case "lte", "max":
+			schema["maxItems"] = int(n)
+		}
+	}
+	switch key {
+	case "oneof":
+		var enum []interface{}
+		for _, v := range strings.Fields(value) {
+			if schema["type"] == "string" {
+				enum = append(enum, v)
+			} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+				enum = append(enum, n)
+			}
+		}
+		schema["enum"] = enum
+	case "email":
+		schema["format"] = "email"
+	case "url", "uri":
+		schema["format"] = "uri"
+	case "uuid", "uuid4":
+		schema["format"] = "uuid"
+	}
+}
+
+// openAPIParamsSchema returns schema of request body containing permitted
+// params of model for action.
+func openAPIParamsSchema(model openAPIModel, action string) map[string]interface{} {
+	full := openAPIStructSchema(model.typ, nil)
+	properties := map[string]interface{}{}
+	for _, param := range model.params[action] {
+		if p, ok := full["properties"].(map[string]interface{})[param]; ok {
+			properties[param] = p
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if action == "create" {
+		var required []string
+		if r, ok := full["required"].([]string); ok {
+			for _, name := range r {
+				if _, ok := properties[name]; ok {
+					required = append(required, name)
+				}
+			}
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	return schema
+}
+
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func openAPIObject(properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func openAPIContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+func openAPIResponse(description string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     openAPIContent(schema),
+	}
+}
+
+func openAPIRefResponse(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/responses/" + name}
+}
+
+func openAPIErrorsResponse(description, item string) map[string]interface{} {
+	return openAPIResponse(description, openAPIObject(map[string]interface{}{
+		"Errors": map[string]interface{}{"type": "array", "items": openAPIRef(item)},
+	}))
+}
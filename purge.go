@@ -0,0 +1,215 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gopsql/psql"
+)
+
+// DefaultRetentionPeriod is the default retention period of soft deleted
+// records, see SetRetentionPeriod().
+const DefaultRetentionPeriod = 30 * 24 * time.Hour
+
+// Number of records purged at a time.
+const purgeBatchSize = 500
+
+// Purge permanently deletes records of all models that have DeletedAt field
+// and were deleted before the retention period, together with their
+// dependents, and deletes expired idempotency keys (see
+// DeleteExpiredIdempotencyKeys()). Returns number of purged records, not
+// including dependents.
+func (backend Backend) Purge() (n int, err error) {
+	before := time.Now().UTC().Add(-backend.retentionPeriod)
+	for _, m := range backend.models {
+		if _, ok := reflect.TypeOf(m.New().Interface()).Elem().FieldByName("DeletedAt"); !ok {
+			continue
+		}
+		for {
+			records := m.NewSlice()
+			err = m.Find().Where(fmt.Sprintf("%s < $1", m.ToColumnName("DeletedAt")), before).
+				OrderBy(m.ToColumnName("Id")).Limit(purgeBatchSize).Query(records.Interface())
+			if err != nil {
+				return
+			}
+			var ids []int
+			for i := 0; i < records.Elem().Len(); i++ {
+				if id, ok := toInt64(fieldInterface(records.Elem().Index(i), "Id")); ok {
+					ids = append(ids, int(id))
+				}
+			}
+			if len(ids) == 0 {
+				break
+			}
+			if err = backend.DeletePermanently(m, ids...); err != nil {
+				return
+			}
+			n += len(ids)
+			if len(ids) < purgeBatchSize {
+				break
+			}
+		}
+	}
+	err = backend.DeleteExpiredIdempotencyKeys()
+	return
+}
+
+// DeletePermanently deletes records of model m with given IDs and their
+// dependents (see HasDependents) in a transaction, with the BeforeDestroy and
+// AfterDestroy hooks of the records called.
+func (backend Backend) DeletePermanently(m *psql.Model, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return backend.Transaction(m, func(tx *psql.Tx) error {
+		var conds Conditions
+		conds.AddIn(m.ToColumnName("Id"), args...)
+		records := m.NewSlice()
+		err := m.Find().Where(conds.String(), conds.Args()...).OrderBy(m.ToColumnName("Id")).
+			QueryInTransaction(tx, records.Interface())
+		if err != nil {
+			return err
+		}
+		for i := 0; i < records.Elem().Len(); i++ {
+			if err := callHook(tx, records.Elem().Index(i).Addr().Interface(), "BeforeDestroy"); err != nil {
+				return err
+			}
+		}
+		if err := backend.deleteInTransaction(tx, m, args...); err != nil {
+			return err
+		}
+		for i := 0; i < records.Elem().Len(); i++ {
+			record := records.Elem().Index(i).Addr().Interface()
+			if err := callHook(tx, record, "AfterDestroy"); err != nil {
+				return err
+			}
+			onCommit(tx, record)
+		}
+		return nil
+	})
+}
+
+// dependents returns dependents of model m (see HasDependents) and the
+// registered models with foreign key field named after m, like AdminId of
+// the sessions and tenant memberships of Admin.
+func (backend Backend) dependents(m *psql.Model) map[string]string {
+	dependents := map[string]string{}
+	foreignKey := m.TypeName() + "Id"
+	for _, dm := range backend.models {
+		if _, ok := reflect.TypeOf(dm.New().Interface()).Elem().FieldByName(foreignKey); ok {
+			dependents[dm.TypeName()] = foreignKey
+		}
+	}
+	if d, ok := m.New().Interface().(HasDependents); ok {
+		for name, foreignKey := range d.Dependents() {
+			dependents[name] = foreignKey
+		}
+	}
+	return dependents
+}
+
+// deleteInTransaction deletes records of model m with given IDs and their
+// dependents, recursively, without calling hooks.
+func (backend Backend) deleteInTransaction(tx *psql.Tx, m *psql.Model, ids ...interface{}) error {
+	return backend.deleteRecursively(tx, m, map[string]map[int64]bool{}, ids...)
+}
+
+// deleteRecursively deletes records of model m with given IDs after their
+// dependents. Records in visited are skipped, so circular dependents end.
+func (backend Backend) deleteRecursively(tx *psql.Tx, m *psql.Model, visited map[string]map[int64]bool, ids ...interface{}) error {
+	name := m.TypeName()
+	if visited[name] == nil {
+		visited[name] = map[int64]bool{}
+	}
+	var unvisited []interface{}
+	for _, id := range ids {
+		if i, ok := toInt64(id); ok && !visited[name][i] {
+			visited[name][i] = true
+			unvisited = append(unvisited, id)
+		}
+	}
+	if len(unvisited) == 0 {
+		return nil
+	}
+	dependents := backend.dependents(m)
+	var names []string
+	for name := range dependents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		dm := backend.ModelByName(name)
+		if dm == nil {
+			return fmt.Errorf("no model named %s", name)
+		}
+		var conds Conditions
+		conds.AddIn(dm.ToColumnName(dependents[name]), unvisited...)
+		if len(backend.dependents(dm)) == 0 {
+			if err := dm.Delete().Where(conds.String(), conds.Args()...).ExecuteInTransaction(tx); err != nil {
+				return err
+			}
+			continue
+		}
+		records := dm.NewSlice()
+		err := dm.Find().Where(conds.String(), conds.Args()...).QueryInTransaction(tx, records.Interface())
+		if err != nil {
+			return err
+		}
+		var dependentIds []interface{}
+		for i := 0; i < records.Elem().Len(); i++ {
+			if id, ok := toInt64(fieldInterface(records.Elem().Index(i), "Id")); ok {
+				dependentIds = append(dependentIds, int(id))
+			}
+		}
+		if err := backend.deleteRecursively(tx, dm, visited, dependentIds...); err != nil {
+			return err
+		}
+	}
+	var conds Conditions
+	conds.AddIn(m.ToColumnName("Id"), unvisited...)
+	return m.Delete().Where(conds.String(), conds.Args()...).ExecuteInTransaction(tx)
+}
+
+// StartPurging calls Purge() every interval in a new goroutine until the
+// returned stop function is called.
+func (backend Backend) StartPurging(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				n, err := backend.Purge()
+				if err != nil {
+					backend.logger.Error("Purge Error:", err)
+				} else if n > 0 {
+					backend.logger.Info("Purged", n, "deleted records")
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// CheckPurge purges deleted records if PURGE=1 environment variable is set.
+func (backend Backend) CheckPurge() {
+	if os.Getenv("PURGE") != "1" {
+		return
+	}
+	n, err := backend.Purge()
+	if err != nil {
+		backend.logger.Fatal(err)
+	}
+	backend.logger.Info("Purged", n, "deleted records")
+	os.Exit(0)
+}
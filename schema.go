@@ -0,0 +1,106 @@
+package backend
+
+import (
+	"reflect"
+
+	"github.com/gopsql/psql"
+)
+
+// ModelSchema describes a registered model for form generation. Names of the
+// properties and the params are in the key case (see SetKeyCase()).
+type ModelSchema struct {
+	Name string
+
+	// JSON Schema (draft 2020-12) generated from the field types and the
+	// validate tags of the model.
+	Schema map[string]interface{}
+
+	// Permitted params of the create and update actions (see HasParams).
+	Params map[string][]string
+}
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// ModelSchemas returns schemas of all registered models.
+func (backend *Backend) ModelSchemas() []ModelSchema {
+	schemas := []ModelSchema{}
+	for _, m := range backend.models {
+		schemas = append(schemas, backend.modelSchema(m))
+	}
+	return schemas
+}
+
+// ModelSchema returns schema of registered model by name. False is returned
+// if there is no such model.
+func (backend *Backend) ModelSchema(name string) (ModelSchema, bool) {
+	m := backend.ModelByName(name)
+	if m == nil {
+		return ModelSchema{}, false
+	}
+	return backend.modelSchema(m), true
+}
+
+func (backend *Backend) modelSchema(m *psql.Model) ModelSchema {
+	name := m.TypeName()
+	record := m.New().Interface()
+	schema := toJSONSchema(openAPIStructSchema(reflect.TypeOf(record).Elem(), writeOnlyFields(record))).(map[string]interface{})
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = name
+	params := map[string][]string{}
+	for _, action := range []string{"create", "update"} {
+		params[action] = modelParams(name, m, action)
+		if backend.keyCase != KeyCaseGo {
+			converted := make([]string, len(params[action]))
+			for i, param := range params[action] {
+				converted[i] = backend.keyCase.fromGo(param)
+			}
+			params[action] = converted
+		}
+	}
+	if backend.keyCase != KeyCaseGo {
+		schema = convertSchemaKeys(schema, backend.keyCase.fromGo).(map[string]interface{})
+	}
+	return ModelSchema{name, schema, params}
+}
+
+// modelParams returns permitted params of model m by name for action, as
+// used by the admins controller or the generic models controller.
+func modelParams(name string, m *psql.Model, action string) []string {
+	if name == "Admin" {
+		return adminParams(m.New().Interface(), action)
+	}
+	return fiberModelsCtrl{}.params(m, action)
+}
+
+// toJSONSchema converts OpenAPI 3.0 schema to JSON Schema: nullable becomes
+// null type, and boolean exclusiveMinimum and exclusiveMaximum become
+// numbers.
+func toJSONSchema(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range v {
+			out[key] = toJSONSchema(value)
+		}
+		if out["nullable"] == true {
+			if t, ok := out["type"].(string); ok {
+				out["type"] = []string{t, "null"}
+			}
+		}
+		delete(out, "nullable")
+		for _, pair := range [][2]string{{"exclusiveMinimum", "minimum"}, {"exclusiveMaximum", "maximum"}} {
+			if out[pair[0]] == true {
+				out[pair[0]] = out[pair[1]]
+				delete(out, pair[1])
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = toJSONSchema(v[i])
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,164 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gopsql/pagination/v2"
+	"github.com/gopsql/psql"
+)
+
+// AddSearch adds condition matching query against the search fields of
+// model m (see Searchable) to conds, and returns ORDER BY expression that
+// ranks the results by relevance, or empty string if ranking is not
+// available. Conds should not be merged after other conditions, since the
+// returned expression refers to the argument by its position.
+//
+// PostgreSQL full text search (tsvector and tsquery) is used for PostgreSQL.
+// SQLite FTS5 is used for SQLite if the table created by SearchSchema()
+// exists. Full text search matches word prefixes. Records containing the
+// query as substring of any of the fields (ILIKE or LIKE) are matched
+// instead if full text search is not available or the query has no letters
+// or digits.
+func (backend Backend) AddSearch(conds *Conditions, m *psql.Model, query string) (rank string) {
+	var fields []string
+	if s, ok := m.New().Interface().(Searchable); ok {
+		fields = s.SearchFields()
+	}
+	if len(fields) == 0 {
+		return
+	}
+	placeholder := fmt.Sprintf("$%d", len(conds.Args())+1)
+	if terms := searchTerms(query); len(terms) > 0 {
+		if !isSQLite(m) {
+			vector := searchVector(m, fields)
+			for i := range terms {
+				terms[i] += ":*"
+			}
+			conds.Add(fmt.Sprintf("%s @@ to_tsquery('simple', $?)", vector), strings.Join(terms, " & "))
+			return fmt.Sprintf("ts_rank(%s, to_tsquery('simple', %s)) DESC", vector, placeholder)
+		}
+		if hasFTS5Table(m) {
+			table := searchTable(m)
+			for i := range terms {
+				terms[i] = `"` + terms[i] + `"*`
+			}
+			conds.Add(fmt.Sprintf("%s IN (SELECT rowid FROM %s WHERE %s MATCH $?)",
+				m.ToColumnName("Id"), table, table), strings.Join(terms, " "))
+			return fmt.Sprintf("(SELECT rank FROM %s WHERE %s MATCH %s AND rowid = %s.%s)",
+				table, table, placeholder, m.TableName(), m.ToColumnName("Id"))
+		}
+	}
+	var likes []string
+	var args []interface{}
+	pattern := "%" + escapeLike(strings.TrimSpace(query)) + "%"
+	for _, field := range fields {
+		likes = append(likes, fmt.Sprintf(`%s %s $? ESCAPE '\'`, m.ToColumnName(field), likeOperator(m)))
+		args = append(args, pattern)
+	}
+	conds.Add("("+strings.Join(likes, " OR ")+")", args...)
+	return
+}
+
+// SearchSchema returns SQL that creates the full text search index of the
+// search fields of model m, which can be used in AfterCreateSchema() of the
+// model. For SQLite, FTS5 virtual table and triggers that keep it up to
+// date are created if FTS5 is available.
+func SearchSchema(m psql.Model) string {
+	var fields []string
+	if s, ok := m.New().Interface().(Searchable); ok {
+		fields = s.SearchFields()
+	}
+	if len(fields) == 0 || m.Connection() == nil {
+		return ""
+	}
+	if !isSQLite(&m) {
+		return fmt.Sprintf("CREATE INDEX %s_search ON %s USING gin (%s);",
+			m.TableName(), m.TableName(), searchVector(&m, fields))
+	}
+	var enabled int
+	if m.Connection().QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled) != nil || enabled != 1 {
+		return ""
+	}
+	var columns, newColumns, oldColumns []string
+	for _, field := range fields {
+		column := m.ToColumnName(field)
+		columns = append(columns, column)
+		newColumns = append(newColumns, "new."+column)
+		oldColumns = append(oldColumns, "old."+column)
+	}
+	table, fts, id := m.TableName(), searchTable(&m), m.ToColumnName("Id")
+	cols := strings.Join(columns, ", ")
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (new.%s, %s);", fts, cols, id, strings.Join(newColumns, ", "))
+	remove := fmt.Sprintf("INSERT INTO %[1]s (%[1]s, rowid, %[2]s) VALUES ('delete', old.%[3]s, %[4]s);", fts, cols, id, strings.Join(oldColumns, ", "))
+	return strings.Join([]string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s, content='%s', content_rowid='%s');", fts, cols, table, id),
+		fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN %s END;", fts, table, insert),
+		fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN %s END;", fts, table, remove),
+		fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE ON %s BEGIN %s %s END;", fts, table, remove, insert),
+	}, "\n")
+}
+
+// orderByRank returns ORDER BY of list query q, with results ranked by
+// search relevance first if rank is not empty and no sort is requested.
+func orderByRank(c FiberCtx, q pagination.PaginationQuerySort, rank string) string {
+	if rank == "" || c.Query("sort") != "" {
+		return q.OrderByValue()
+	}
+	return rank + ", " + q.OrderByValue()
+}
+
+func searchTable(m *psql.Model) string {
+	return m.TableName() + "_fts"
+}
+
+func searchVector(m *psql.Model, fields []string) string {
+	var columns []string
+	for _, field := range fields {
+		columns = append(columns, fmt.Sprintf("coalesce(%s, '')", m.ToColumnName(field)))
+	}
+	return fmt.Sprintf("to_tsvector('simple', %s)", strings.Join(columns, " || ' ' || "))
+}
+
+// Results of hasFTS5Table() for each connection and table.
+var fts5Tables = struct {
+	sync.Mutex
+	exists map[fts5Table]bool
+}{exists: map[fts5Table]bool{}}
+
+type fts5Table struct {
+	conn  interface{}
+	table string
+}
+
+// hasFTS5Table returns true if the FTS5 table of model m exists. The result
+// is cached for the connection of the model, so the table should be created
+// (see SearchSchema()) before the first search.
+func hasFTS5Table(m *psql.Model) bool {
+	key := fts5Table{m.Connection(), searchTable(m)}
+	fts5Tables.Lock()
+	exists, ok := fts5Tables.exists[key]
+	fts5Tables.Unlock()
+	if ok {
+		return exists
+	}
+	var n int
+	err := m.Connection().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1",
+		key.table).Scan(&n)
+	if err != nil {
+		return false
+	}
+	fts5Tables.Lock()
+	fts5Tables.exists[key] = n > 0
+	fts5Tables.Unlock()
+	return n > 0
+}
+
+// searchTerms splits query into words containing only letters and digits.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,247 @@
+package backend
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/gopsql/psql"
+)
+
+// TenantResolution is a way to resolve the tenant of a request, see
+// SetTenantResolutions().
+type TenantResolution int
+
+const (
+	// First label of the host name with at least three labels, like acme
+	// of acme.example.com, matched with Subdomain of the tenants.
+	TenantBySubdomain TenantResolution = iota
+
+	// The X-Tenant header, matched with Subdomain of the tenants.
+	TenantByHeader
+
+	// The first tenant the authenticated admin is member of.
+	TenantByMembership
+)
+
+// DefaultTenantResolutions resolves the tenant by subdomain, then by the
+// X-Tenant header, then by membership of the admin. Hosts whose subdomain is
+// not a tenant fall through to the next resolution.
+var DefaultTenantResolutions = []TenantResolution{TenantBySubdomain, TenantByHeader, TenantByMembership}
+
+// FiberTenantId returns ID of the tenant of the request, resolved in the
+// order of the tenant resolutions (see SetTenantResolutions()), or zero if
+// the Tenant model is not added (see AddModelTenant()) or no tenant is
+// resolved. Subdomains that match no tenant, like www or api, are skipped.
+// Tenant of the header must exist, and the authenticated admin must be
+// member of the tenant resolved by subdomain or header (see
+// IsTenantMember()), otherwise ErrNoRows() of the database connection is
+// returned.
+// The ID is cached in the current request.
+func (backend Backend) FiberTenantId(c FiberCtx) (id int, err error) {
+	if id, ok := c.Locals(getName(c, "CurrentTenantId")).(int); ok {
+		return id, nil
+	}
+	m := backend.ModelByName(getName(c, "Tenant"))
+	if m == nil {
+		return
+	}
+	m = m.Quiet()
+	adminId, _, authenticated := backend.FiberGetAdminAndSessionId(c)
+	for _, resolution := range backend.tenantResolutions {
+		var subdomain string
+		switch resolution {
+		case TenantBySubdomain:
+			subdomain = hostSubdomain(c.Get("Host"))
+		case TenantByHeader:
+			subdomain = c.Get("X-Tenant")
+		case TenantByMembership:
+			if authenticated {
+				id, err = backend.firstTenantId(c, adminId)
+			}
+		}
+		if subdomain != "" {
+			err = m.Select(m.ToColumnName("Id")).WHERE("Subdomain", "=", subdomain).QueryRow(&id)
+			if resolution == TenantBySubdomain && backend.IsErrNoRows(err) {
+				id, err = 0, nil
+				continue
+			}
+			if err == nil && authenticated {
+				var member bool
+				if member, err = backend.IsTenantMember(c, id, adminId); err == nil && !member {
+					err = backend.dbConn.ErrNoRows()
+				}
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+		if id != 0 {
+			break
+		}
+	}
+	c.Locals(getName(c, "CurrentTenantId"), id)
+	return
+}
+
+// IsTenantMember returns true if admin is member of tenant, or admin is not
+// member of any tenant and cross-tenant admins are allowed, see
+// SetCrossTenantAdmins().
+func (backend Backend) IsTenantMember(c FiberCtx, tenantId, adminId int) (bool, error) {
+	m := backend.ModelByName(getName(c, "TenantMembership"))
+	if m == nil {
+		return true, nil
+	}
+	m = m.Quiet()
+	adminIdColumn := m.ToColumnName(getName(c, "AdminId"))
+	sql := fmt.Sprintf("%s = $1 AND %s = $2", adminIdColumn, m.ToColumnName("TenantId"))
+	if exists, err := m.Where(sql, adminId, tenantId).Exists(); err != nil || exists || !backend.crossTenantAdmins {
+		return exists, err
+	}
+	exists, err := m.Where(fmt.Sprintf("%s = $1", adminIdColumn), adminId).Exists()
+	return !exists, err
+}
+
+// firstTenantId returns ID of the first tenant admin is member of, or zero.
+func (backend Backend) firstTenantId(c FiberCtx, adminId int) (id int, err error) {
+	m := backend.ModelByName(getName(c, "TenantMembership"))
+	if m == nil {
+		return
+	}
+	m = m.Quiet()
+	err = m.Select(m.ToColumnName("TenantId")).WHERE(getName(c, "AdminId"), "=", adminId).
+		OrderBy(m.ToColumnName("Id")).Limit(1).QueryRow(&id)
+	if backend.IsErrNoRows(err) {
+		err = nil
+	}
+	return
+}
+
+// hostSubdomain returns the first label of host if it has at least three
+// labels.
+func hostSubdomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 3 {
+		return ""
+	}
+	return labels[0]
+}
+
+// FiberTenantConditions returns condition limiting records of model m to
+// the tenant of the request (see FiberTenantId()): records with TenantId of
+// the tenant if the model has TenantId field, or members of the tenant if
+// the model is the admin model. InputErrors is returned for the models with
+// TenantId field if there is no tenant. No conditions are returned if the
+// Tenant model is not added.
+func (backend Backend) FiberTenantConditions(c FiberCtx, m *psql.Model) (conds Conditions, err error) {
+	scoped := hasTenantId(m)
+	if backend.ModelByName(getName(c, "Tenant")) == nil || !scoped && m.TypeName() != getName(c, "Admin") {
+		return
+	}
+	id, err := backend.FiberTenantId(c)
+	if err != nil {
+		return
+	}
+	if scoped {
+		if id == 0 {
+			err = NewInputErrors("Tenant", "required")
+			return
+		}
+		conds.Add(fmt.Sprintf("%s = $?", m.ToColumnName("TenantId")), id)
+	} else if mm := backend.ModelByName(getName(c, "TenantMembership")); mm != nil && id != 0 {
+		conds.Add(fmt.Sprintf("%s IN (SELECT %s FROM %s WHERE %s = $?)", m.ToColumnName("Id"),
+			mm.ToColumnName(getName(c, "AdminId")), mm.TableName(), mm.ToColumnName("TenantId")), id)
+	}
+	return
+}
+
+// FiberFind finds record of model m by id within the tenant of the request,
+// see FiberTenantConditions().
+func (backend Backend) FiberFind(c FiberCtx, m *psql.Model, id interface{}, record interface{}) error {
+	return backend.fiberFindInTransaction(c, nil, m, id, record)
+}
+
+// fiberFindInTransaction is like FiberFind but finds record in tx if it is
+// not nil.
+func (backend Backend) fiberFindInTransaction(c FiberCtx, tx *psql.Tx, m *psql.Model, id interface{}, record interface{}) error {
+	conds, err := backend.FiberTenantConditions(c, m)
+	if err != nil {
+		return err
+	}
+	conds.Add(fmt.Sprintf("%s = $?", m.ToColumnName("Id")), id)
+	find := m.Find().Where(conds.String(), conds.Args()...)
+	if tx != nil {
+		return find.QueryInTransaction(tx, record)
+	}
+	return find.Query(record)
+}
+
+// fiberTenantChanges assigns the tenant of the request to TenantId of
+// record of model m and returns the changes, if the model has TenantId
+// field.
+func (backend Backend) fiberTenantChanges(c FiberCtx, m *psql.Model, record interface{}) ([]interface{}, error) {
+	if backend.ModelByName(getName(c, "Tenant")) == nil || !hasTenantId(m) {
+		return nil, nil
+	}
+	id, err := backend.FiberTenantId(c)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		return nil, NewInputErrors("Tenant", "required")
+	}
+	return m.Assign(record, "TenantId", id)
+}
+
+// fiberAddTenantMemberInTransaction makes admin, the created record of
+// model m if it is the admin model, member of the tenant of the request.
+func (backend Backend) fiberAddTenantMemberInTransaction(c FiberCtx, tx *psql.Tx, m *psql.Model, admin interface{}) error {
+	mm := backend.ModelByName(getName(c, "TenantMembership"))
+	if mm == nil || m.TypeName() != getName(c, "Admin") {
+		return nil
+	}
+	tenantId, err := backend.FiberTenantId(c)
+	if err != nil || tenantId == 0 {
+		return err
+	}
+	return mm.Insert(
+		"TenantId", tenantId,
+		getName(c, "AdminId"), fieldInterface(reflect.ValueOf(admin).Elem(), "Id"),
+	).ExecuteInTransaction(tx)
+}
+
+// IsUnique returns true if no other record of the model of record has the
+// same value of field, among the records of the same tenant if the model
+// has TenantId field. It is used by the uniqueness validation of the models
+// that don't have IsUnique(*Backend, string) method. Returns false if the
+// model is not added or the query fails.
+func (backend Backend) IsUnique(record interface{}, field string) bool {
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	for _, m := range backend.models {
+		if reflect.TypeOf(m.New().Interface()).Elem() != rv.Type() {
+			continue
+		}
+		var conds Conditions
+		conds.Add(fmt.Sprintf("%s = $?", m.ToColumnName(field)), fieldInterface(rv, field))
+		conds.Add(fmt.Sprintf("%s != $?", m.ToColumnName("Id")), fieldInterface(rv, "Id"))
+		if hasTenantId(m) {
+			conds.Add(fmt.Sprintf("%s = $?", m.ToColumnName("TenantId")), fieldInterface(rv, "TenantId"))
+		}
+		exists, err := m.Where(conds.String(), conds.Args()...).Exists()
+		return err == nil && !exists
+	}
+	return false
+}
+
+// hasTenantId returns true if model m has TenantId field.
+func hasTenantId(m *psql.Model) bool {
+	_, ok := reflect.TypeOf(m.New().Interface()).Elem().FieldByName("TenantId")
+	return ok
+}
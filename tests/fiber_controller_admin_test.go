@@ -3,6 +3,7 @@ package backend
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
@@ -66,6 +67,18 @@ func testAdmins(t *test) {
 	t.Request(httptest.NewRequest("GET", adminPath, nil), 200, &showAdmin, token)
 	t.Bool("admin equal", newAdmin == showAdmin, true)
 
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/admins", nil),
+		httptest.NewRequest("GET", adminPath, nil),
+		httptest.NewRequest("POST", "/admins", strings.NewReader(`{ "Name": "writeonly", "Password": "123123" }`)),
+		httptest.NewRequest("PATCH", adminPath, strings.NewReader(`{ "Password": "123123" }`)),
+		httptest.NewRequest("GET", "/me", nil),
+	} {
+		body := t.RequestBody(req, 200, token)
+		t.Bool("password is hidden", strings.Contains(body, "Password"), false)
+	}
+	t.Request(httptest.NewRequest("DELETE", "/admins/3", nil), 200, nil, token)
+
 	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &list, token)
 	t.Int("list size", len(list.Admins), 2)
 	t.String("admin name", list.Admins[0].Name, "admin")
@@ -78,17 +91,29 @@ func testAdmins(t *test) {
 	t.Int("list size", len(list.Admins), 1)
 	t.String("admin name", list.Admins[0].Name, "foobar")
 
-	t.Request(httptest.NewRequest("PUT", adminPath, strings.NewReader(`{ "Name": "ADMIN" }`)), 400, &resBody, token)
+	t.Request(httptest.NewRequest("PATCH", adminPath, strings.NewReader(`{ "Name": "ADMIN" }`)), 400, &resBody, token)
 	t.String("response", string(resBody),
 		`{"Errors":[{"FullName":"Admin.Name","Name":"Name","Kind":"string","Type":"uniqueness","Param":""}]}`)
 
 	var updateAdmin Admin
-	t.Request(httptest.NewRequest("PUT", adminPath, strings.NewReader(`{ "Name": "root" }`)), 200, &updateAdmin, token)
+	t.Request(httptest.NewRequest("PATCH", adminPath, strings.NewReader(`{ "Name": "root" }`)), 200, &updateAdmin, token)
 	t.String("admin name", updateAdmin.Name, "root")
 	t.Bool("admin deleted at is null", updateAdmin.DeletedAt == nil, true)
 
 	t.Request(httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{ "Name": "root", "Password": "123123" }`)), 200, nil)
 
+	var unchangedAdmin Admin
+	t.Request(httptest.NewRequest("PATCH", adminPath, strings.NewReader(`{ "Name": "root" }`)), 200, &unchangedAdmin, token)
+	t.Bool("unchanged admin is not updated", unchangedAdmin.UpdatedAt.Equal(updateAdmin.UpdatedAt), true)
+
+	var putAdmin Admin
+	t.Request(httptest.NewRequest("PUT", adminPath, strings.NewReader(`{ "Name": "root", "Password": "456456" }`)), 200, &putAdmin, token)
+	t.String("admin name", putAdmin.Name, "root")
+	t.Bool("updated at changed", putAdmin.UpdatedAt.Equal(updateAdmin.UpdatedAt), false)
+	t.Request(httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{ "Name": "root", "Password": "123123" }`)), 400, nil)
+	t.Request(httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{ "Name": "root", "Password": "456456" }`)), 200, nil)
+	t.Request(httptest.NewRequest("PATCH", adminPath, strings.NewReader(`{ "Password": "123123" }`)), 200, nil, token)
+
 	var deletedAdmin Admin
 	t.Request(httptest.NewRequest("DELETE", adminPath, nil), 200, &deletedAdmin, token)
 	t.Bool("admin deleted at is not null", deletedAdmin.DeletedAt != nil, true)
@@ -103,3 +128,412 @@ func testAdmins(t *test) {
 
 	t.Request(httptest.NewRequest("POST", "/sign-in", strings.NewReader(`{ "Name": "root", "Password": "123123" }`)), 200, nil)
 }
+
+func TestAdminsFilter(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsFilter(t)
+	})
+}
+
+func testAdminsFilter(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo", "bar", "foobar")
+
+	var list struct {
+		Admins []struct {
+			Id   int
+			Name string
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bname%5D=foo", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 1)
+	t.String("admin name", list.Admins[0].Name, "foo")
+
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bname%5D%5Bin%5D=foo,bar", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bname%5D%5Blike%5D=oo", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bid%5D%5Bnin%5D=1,2", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bpassword%5D=foo", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"filter[password]","Name":"password","Kind":"string","Type":"filter","Param":"eq"}]}`)
+
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bname%5D%5Bgt%5D=foo", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"filter[name][gt]","Name":"name","Kind":"string","Type":"operator","Param":"gt"}]}`)
+
+	t.Request(httptest.NewRequest("GET", "/admins?filter%5Bid%5D=foo", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"filter[id]","Name":"id","Kind":"int","Type":"invalid","Param":"eq"}]}`)
+}
+
+func TestAdminsSearch(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsSearch(t)
+	})
+}
+
+func testAdminsSearch(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "john smith", "jane doe", "johnny")
+
+	var list struct {
+		Admins []struct {
+			Id   int
+			Name string
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/admins?query=joh", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+
+	t.Request(httptest.NewRequest("GET", "/admins?query=john+smith", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 1)
+	t.String("admin name", list.Admins[0].Name, "john smith")
+
+	t.Request(httptest.NewRequest("GET", "/admins?query=DOE", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 1)
+	t.String("admin name", list.Admins[0].Name, "jane doe")
+
+	t.Request(httptest.NewRequest("GET", "/admins?query=nobody", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 0)
+
+	t.Request(httptest.NewRequest("GET", "/admins?query=%25%25", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 0)
+}
+
+func TestAdminsCursorPagination(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsCursorPagination(t)
+	})
+}
+
+func testAdminsCursorPagination(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo", "bar", "foobar")
+
+	var list struct {
+		Admins []struct {
+			Id   int
+			Name string
+		}
+		Pagination backend.CursorPaginationResult
+	}
+	t.Request(httptest.NewRequest("GET", "/admins?pagination=cursor&per=2", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+	t.Int("admin id", list.Admins[0].Id, 1)
+	t.Int("admin id", list.Admins[1].Id, 2)
+	t.String("prev cursor", list.Pagination.Prev, "")
+	t.Bool("next cursor is present", list.Pagination.Next != "", true)
+
+	t.Request(httptest.NewRequest("GET", "/admins?pagination=cursor&per=2&after="+list.Pagination.Next, nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+	t.Int("admin id", list.Admins[0].Id, 3)
+	t.Int("admin id", list.Admins[1].Id, 4)
+	t.String("next cursor", list.Pagination.Next, "")
+	t.Bool("prev cursor is present", list.Pagination.Prev != "", true)
+
+	t.Request(httptest.NewRequest("GET", "/admins?pagination=cursor&per=2&before="+list.Pagination.Prev, nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+	t.Int("admin id", list.Admins[0].Id, 1)
+	t.Int("admin id", list.Admins[1].Id, 2)
+	t.String("prev cursor", list.Pagination.Prev, "")
+
+	t.Request(httptest.NewRequest("GET", "/admins?pagination=cursor&per=2&sort=name&order=desc", nil), 200, &list, token)
+	t.String("admin name", list.Admins[0].Name, "foobar")
+	t.String("admin name", list.Admins[1].Name, "foo")
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("GET", "/admins?pagination=cursor&after=foo", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Cursor","Name":"Cursor","Kind":"string","Type":"invalid","Param":""}]}`)
+}
+
+func TestAdminsExport(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsExport(t)
+	})
+}
+
+func testAdminsExport(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo", "bar")
+
+	body := t.RequestBody(httptest.NewRequest("GET", "/admins/export.csv?sort=name", nil), 200, token)
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	t.Int("csv lines", len(lines), 4)
+	t.Bool("csv header", strings.HasPrefix(lines[0], "Id,Name,"), true)
+	t.Bool("csv first row", strings.HasPrefix(lines[1], "1,admin,"), true)
+	t.Bool("csv second row", strings.HasPrefix(lines[2], "3,bar,"), true)
+	t.Bool("csv has no password", strings.Contains(lines[0], "Password"), false)
+	t.Bool("csv has no password hash", strings.Contains(body, "$2a$"), false)
+
+	body = t.RequestBody(httptest.NewRequest("GET", "/admins/export.csv?filter%5Bname%5D=nobody", nil), 200, token)
+	lines = strings.Split(strings.TrimSpace(body), "\n")
+	t.Int("empty csv lines", len(lines), 1)
+	t.Bool("empty csv header", strings.HasPrefix(lines[0], "Id,Name,"), true)
+
+	t.createAdmins(token, "=1+1")
+	body = t.RequestBody(httptest.NewRequest("GET", "/admins/export.csv?filter%5Bname%5D=%3D1%2B1", nil), 200, token)
+	lines = strings.Split(strings.TrimSpace(body), "\n")
+	t.Int("formula csv lines", len(lines), 2)
+	t.Bool("csv formula is escaped", strings.HasPrefix(lines[1], "4,'=1+1,"), true)
+
+	body = t.RequestBody(httptest.NewRequest("GET", "/admins/export.ndjson?filter%5Bname%5D=foo", nil), 200, token)
+	lines = strings.Split(strings.TrimSpace(body), "\n")
+	t.Int("ndjson lines", len(lines), 1)
+	var admin struct{ Name string }
+	json.Unmarshal([]byte(lines[0]), &admin)
+	t.String("admin name", admin.Name, "foo")
+	t.Bool("ndjson has no password", strings.Contains(lines[0], "Password"), false)
+
+	body = t.RequestBody(httptest.NewRequest("GET", "/admins/export.xlsx", nil), 200, token)
+	t.Bool("xlsx is zip", strings.HasPrefix(body, "PK"), true)
+
+	t.RequestBody(httptest.NewRequest("GET", "/admins/export.pdf", nil), 400, token)
+}
+
+func TestAdminsBulk(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsBulk(t)
+	})
+}
+
+func testAdminsBulk(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo", "bar")
+
+	var result backend.BulkResult
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/destroy", strings.NewReader(`{ "Ids": [2, 3, 100] }`)), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Id","Name":"Id","Kind":"string","Type":"not_found","Param":"","Index":2}]}`)
+
+	var list struct {
+		Admins []struct{ Id int }
+	}
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 3)
+
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/destroy", strings.NewReader(`{ "Ids": [2, 100, 3], "Mode": "best-effort" }`)), 200, &result, token)
+	t.Int("succeeded", len(result.Ids), 2)
+	t.Int("failed", len(result.Errors), 1)
+	t.Int("failed index", result.Errors[0].Index, 1)
+
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 1)
+
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/restore", strings.NewReader(`{ "Ids": [2, 3] }`)), 200, &result, token)
+	t.Int("succeeded", len(result.Ids), 2)
+
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 3)
+
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/update", strings.NewReader(`{ "Items": [`+
+		`{ "Id": 2, "Name": "foo2", "Password": "123123" }, { "Id": 3, "Name": "", "Password": "123123" }] }`)), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Admin.Name","Name":"Name","Kind":"string","Type":"gt","Param":"0","Index":1}]}`)
+
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/update", strings.NewReader(`{ "Items": [`+
+		`{ "Id": 2, "Name": "foo2", "Password": "123123" }, { "Id": 3, "Name": "bar2", "Password": "123123" }] }`)), 200, &result, token)
+	t.Int("succeeded", len(result.Ids), 2)
+
+	var admin struct{ Name string }
+	t.Request(httptest.NewRequest("GET", "/admins/3", nil), 200, &admin, token)
+	t.String("admin name", admin.Name, "bar2")
+
+	t.Request(httptest.NewRequest("POST", "/admins/bulk/update", strings.NewReader(`{ "Items": [`+
+		`{ "Id": 2, "Name": "baz", "Password": "123123" }, { "Id": 3, "Name": "BAZ", "Password": "123123" }] }`)), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Name","Name":"Name","Kind":"string","Type":"unique","Param":"","Index":1}]}`)
+
+	t.Request(httptest.NewRequest("GET", "/admins/2", nil), 200, &admin, token)
+	t.String("admin name", admin.Name, "foo2")
+}
+
+func TestAdminsETag(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsETag(t)
+	})
+}
+
+func testAdminsETag(t *test) {
+	token := t.signIn()
+
+	req := httptest.NewRequest("GET", "/admins/1", nil)
+	req.Header.Set("Authorization", token.Token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get("ETag")
+	t.Bool("etag is present", etag != "", true)
+
+	req = httptest.NewRequest("PATCH", "/admins/1", strings.NewReader(`{ "Name": "root" }`))
+	req.Header.Set("If-Match", `"1-foobar"`)
+	var admin struct{ Name string }
+	t.Request(req, 412, &admin, token)
+	t.String("admin name", admin.Name, "admin")
+
+	req = httptest.NewRequest("DELETE", "/admins/1", nil)
+	req.Header.Set("If-Match", `"1-foobar"`)
+	t.Request(req, 412, nil, token)
+
+	req = httptest.NewRequest("PATCH", "/admins/1", strings.NewReader(`{ "Name": "root" }`))
+	req.Header.Set("If-Match", "W/"+etag)
+	t.Request(req, 412, nil, token)
+
+	req = httptest.NewRequest("PATCH", "/admins/1", strings.NewReader(`{ "Name": "root" }`))
+	req.Header.Set("If-Match", etag)
+	t.Request(req, 200, &admin, token)
+	t.String("admin name", admin.Name, "root")
+
+	req = httptest.NewRequest("PATCH", "/admins/1", strings.NewReader(`{ "Name": "admin" }`))
+	req.Header.Set("If-Match", etag)
+	t.Request(req, 412, &admin, token)
+	t.String("admin name", admin.Name, "root")
+}
+
+func TestAdminsPurge(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsPurge(t)
+	})
+}
+
+func testAdminsPurge(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo", "bar")
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("DELETE", "/admins/1/permanently", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Id","Name":"Id","Kind":"string","Type":"self","Param":""}]}`)
+
+	t.Request(httptest.NewRequest("DELETE", "/admins/3/permanently", nil), 204, nil, token)
+	t.Request(httptest.NewRequest("GET", "/admins/3", nil), 404, nil, token)
+
+	t.Request(httptest.NewRequest("DELETE", "/admins/2", nil), 200, nil, token)
+
+	n, err := backend.Default.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("purged", n, 0)
+
+	backend.Default.SetRetentionPeriod(0)
+	defer backend.Default.SetRetentionPeriod(backend.DefaultRetentionPeriod)
+	n, err = backend.Default.Purge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("purged", n, 1)
+	t.Request(httptest.NewRequest("GET", "/admins/2", nil), 404, nil, token)
+
+	t.createAdmins(token, "foo")
+}
+
+func TestAdminsFieldsAndIncludes(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsFieldsAndIncludes(t)
+	})
+}
+
+func testAdminsFieldsAndIncludes(t *test) {
+	token := t.signIn()
+
+	t.createAdmins(token, "foo")
+
+	var list struct {
+		Admins []json.RawMessage
+	}
+	t.Request(httptest.NewRequest("GET", "/admins?fields=Id,Name", nil), 200, &list, token)
+	t.Int("list size", len(list.Admins), 2)
+	t.String("admin", string(list.Admins[0]), `{"Id":1,"Name":"admin"}`)
+	t.String("admin", string(list.Admins[1]), `{"Id":2,"Name":"foo"}`)
+
+	var listWithSessions struct {
+		Admins []struct {
+			Id       int
+			Sessions []struct {
+				AdminId   int
+				SessionId string
+			}
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/admins?include=sessions", nil), 200, &listWithSessions, token)
+	t.Int("list size", len(listWithSessions.Admins), 2)
+	t.Int("sessions size", len(listWithSessions.Admins[0].Sessions), 1)
+	t.Int("session admin id", listWithSessions.Admins[0].Sessions[0].AdminId, 1)
+	t.String("session id is hidden", listWithSessions.Admins[0].Sessions[0].SessionId, "")
+	t.Int("sessions size", len(listWithSessions.Admins[1].Sessions), 0)
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("GET", "/admins/2?fields=Name", nil), 200, &resBody, token)
+	t.String("response", string(resBody), `{"Name":"foo"}`)
+
+	t.Request(httptest.NewRequest("GET", "/me?fields=Name&include=Sessions", nil), 200, &listWithSessions.Admins[0], token)
+	t.Int("sessions size", len(listWithSessions.Admins[0].Sessions), 1)
+
+	t.Request(httptest.NewRequest("GET", "/admins?include=foo", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"include","Name":"include","Kind":"string","Type":"invalid","Param":"foo"}]}`)
+}
+
+func TestAdminsSessions(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testAdminsSessions(t)
+	})
+}
+
+func testAdminsSessions(t *test) {
+	token, other := t.signIn(), t.signIn()
+
+	var list struct {
+		Sessions []struct {
+			Id        int
+			AdminId   int
+			SessionId string
+			UserAgent string
+		}
+		Current int
+	}
+	t.Request(httptest.NewRequest("GET", "/admins/1/sessions", nil), 200, &list, token)
+	t.Int("sessions size", len(list.Sessions), 2)
+	t.Int("current session", list.Current, 1)
+	t.String("session id is hidden", list.Sessions[0].SessionId, "")
+
+	t.Request(httptest.NewRequest("GET", "/admins/1/sessions?per=1&sort=created_at&order=asc", nil), 200, &list, token)
+	t.Int("sessions size", len(list.Sessions), 1)
+	t.Int("session id", list.Sessions[0].Id, 1)
+
+	t.Request(httptest.NewRequest("GET", "/admins/2/sessions", nil), 404, nil, token)
+
+	t.Request(httptest.NewRequest("DELETE", "/admins/1/sessions/2", nil), 204, nil, token)
+	t.Request(httptest.NewRequest("POST", "/sign-out", nil), 401, nil, other)
+	t.Request(httptest.NewRequest("DELETE", "/admins/1/sessions/2", nil), 404, nil, token)
+
+	t.Request(httptest.NewRequest("GET", "/admins/1/sessions", nil), 200, &list, token)
+	t.Int("sessions size", len(list.Sessions), 1)
+
+	t.Request(httptest.NewRequest("DELETE", "/admins/1/sessions", nil), 204, nil, token)
+	t.Request(httptest.NewRequest("GET", "/admins/1/sessions", nil), 401, nil, token)
+}
@@ -0,0 +1,187 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testGraphQL(t)
+		})
+	})
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage
+	Errors []struct {
+		Message    string
+		Path       []string
+		Extensions map[string]interface{}
+	}
+}
+
+func testGraphQL(t *test) {
+	token := t.signIn()
+
+	graphQL := func(query string, variables map[string]interface{}) (ret graphQLResponse) {
+		t.Helper()
+		t.Request(httptest.NewRequest("POST", "/graphql", asJson(map[string]interface{}{
+			"query":     query,
+			"variables": variables,
+		})), 200, &ret, token)
+		return
+	}
+
+	t.Request(httptest.NewRequest("POST", "/graphql", asJson(map[string]interface{}{
+		"query": "{ posts { Records { Id } } }",
+	})), 401, nil)
+
+	ret := graphQL(`mutation CreatePost($title: String!) {
+		first: createPost(input: {Title: $title, Views: 10}) { Id Title }
+		second: createPost(input: {Title: "Second"}) { Id }
+		invalid: createPost(input: {Title: ""}) { Id }
+	}`, map[string]interface{}{"title": "  First  "})
+	t.String("first", string(ret.Data["first"]), `{"Id":1,"Title":"First"}`)
+	t.String("second", string(ret.Data["second"]), `{"Id":2}`)
+	t.String("invalid", string(ret.Data["invalid"]), "null")
+	t.Int("errors", len(ret.Errors), 1)
+	t.String("error path", ret.Errors[0].Path[0], "invalid")
+	t.Bool("error status", ret.Errors[0].Extensions["Status"] == float64(400), true)
+	t.Bool("input errors", ret.Errors[0].Extensions["Errors"] != nil, true)
+
+	ret = graphQL(`query {
+		posts(sort: "id", order: desc, per: 1, filter: {views: {gte: 10}}) {
+			Records { Id title: Title __typename }
+		}
+		post(id: 2) { Id Title }
+		missing: post(id: 100) { Id }
+	}`, nil)
+	t.String("posts", string(ret.Data["posts"]), `{"Records":[{"Id":1,"title":"First","__typename":"Post"}]}`)
+	t.String("post", string(ret.Data["post"]), `{"Id":2,"Title":"Second"}`)
+	t.String("missing", string(ret.Data["missing"]), "null")
+	t.Int("errors", len(ret.Errors), 1)
+	t.String("not found", ret.Errors[0].Message, "Not Found")
+	t.Bool("not found status", ret.Errors[0].Extensions["Status"] == float64(404), true)
+
+	ret = graphQL(`query {
+		posts(sort: "id") { Records { ...PostFields ... on Post { Views } } }
+	}
+	fragment PostFields on Post { Id ... { Title } }`, nil)
+	t.String("fragments", string(ret.Data["posts"]),
+		`{"Records":[{"Id":1,"Title":"First","Views":10},{"Id":2,"Title":"Second","Views":0}]}`)
+
+	ret = graphQL(`{ posts { Records { ...Unknown } } }`, nil)
+	t.String("unknown fragment", ret.Errors[0].Message, `Unknown fragment "Unknown".`)
+
+	ret = graphQL(`query Admins($name: String) {
+		admins(query: $name) { Records { Name Password Sessions { Id } } }
+	}`, map[string]interface{}{"name": "admin"})
+	t.Int("write-only field", len(ret.Errors), 1)
+	t.String("write-only field error", ret.Errors[0].Message, `Cannot query field "Password" on type "Admin".`)
+
+	ret = graphQL(`{ admins { Records { Name Sessions { Id } } } }`, nil)
+	t.String("admins", string(ret.Data["admins"]), `{"Records":[{"Name":"admin","Sessions":[{"Id":1}]}]}`)
+
+	ret = graphQL(`mutation {
+		updatePost(id: 2, input: {Views: 20}) { Id Views }
+		destroyPost(id: 1) { Id }
+	}`, nil)
+	t.String("update", string(ret.Data["updatePost"]), `{"Id":2,"Views":20}`)
+	t.String("destroy", string(ret.Data["destroyPost"]), `{"Id":1}`)
+	t.Int("no errors", len(ret.Errors), 0)
+
+	ret = graphQL(`{ post(id: 1) { Id } }`, nil)
+	t.String("destroyed", string(ret.Data["post"]), "null")
+
+	ret = graphQL(`{ posts { Records { Id } `, nil)
+	t.Bool("syntax error", ret.Data == nil && len(ret.Errors) == 1, true)
+
+	ret = graphQL(`{ comments { Records { Id } } }`, nil)
+	t.String("unknown field", ret.Errors[0].Message, `Cannot query field "comments" on type "Query".`)
+
+	ret = graphQL(`{ post(slug: "first") { Id } }`, nil)
+	t.String("unknown argument", ret.Errors[0].Message, `Unknown argument "slug".`)
+
+	ret = graphQL(`{
+		__schema { queryType { name } mutationType { name } types { name } }
+		post: __type(name: "Post") { kind fields { name type { name } } }
+		admin: __type(name: "Admin") { fields { name type { kind ofType { name } } } }
+		input: __type(name: "PostCreateInput") { kind inputFields { name } }
+		unknown: __type(name: "Comment") { name }
+	}`, nil)
+	t.Int("no errors", len(ret.Errors), 0)
+	var schema struct {
+		QueryType    struct{ Name string }
+		MutationType struct{ Name string }
+		Types        []struct{ Name string }
+	}
+	json.Unmarshal(ret.Data["__schema"], &schema)
+	t.String("query type", schema.QueryType.Name, "Query")
+	t.String("mutation type", schema.MutationType.Name, "Mutation")
+	var types []string
+	for _, typ := range schema.Types {
+		types = append(types, typ.Name)
+	}
+	t.Bool("types", strings.Contains(strings.Join(types, ","), "Post,PostCreateInput,PostList,PostUpdateInput"), true)
+	t.String("post type", string(ret.Data["post"]), `{"kind":"OBJECT","fields":[`+
+		`{"name":"Id","type":{"name":"Int"}},{"name":"Title","type":{"name":"String"}},{"name":"Views","type":{"name":"Int"}},`+
+		`{"name":"CreatedAt","type":{"name":"String"}},{"name":"UpdatedAt","type":{"name":"String"}}]}`)
+	t.Bool("write-only field", strings.Contains(string(ret.Data["admin"]), "Password"), false)
+	t.Bool("relation", strings.Contains(string(ret.Data["admin"]),
+		`{"name":"Sessions","type":{"kind":"LIST","ofType":{"name":"AdminSession"}}}`), true)
+	t.String("input type", string(ret.Data["input"]), `{"kind":"INPUT_OBJECT","inputFields":[{"name":"Title"},{"name":"Views"}]}`)
+	t.String("unknown type", string(ret.Data["unknown"]), "null")
+
+	ret = graphQL(introspectionQuery, nil)
+	t.Int("introspection query errors", len(ret.Errors), 0)
+
+	ret = graphQL(`{ __type(name: "Post") { name owner } }`, nil)
+	t.String("unknown introspection field", ret.Errors[0].Message, `Cannot query field "owner" on type "__Type".`)
+
+	var get graphQLResponse
+	t.Request(httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ post(id: 2) { Id } }`), nil), 200, &get, token)
+	t.String("get", string(get.Data["post"]), `{"Id":2}`)
+	t.Request(httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`mutation { destroyPost(id: 2) { Id } }`), nil), 200, &get, token)
+	t.String("get mutation", get.Errors[0].Message, "Can only perform a mutation operation from a POST request.")
+}
+
+// introspectionQuery is the introspection query of GraphiQL.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) {
+    name description
+    args { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated deprecationReason
+  }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue {
+  name description
+  type { ...TypeRef }
+  defaultValue
+}
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}
+`
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestHTML(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testHTML(t)
+		})
+	})
+}
+
+func testHTML(t *test) {
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+
+	var cookie *http.Cookie
+	proto := "https"
+	request := func(method, path string, form url.Values, expectedStatus int) (body, location string) {
+		t.Helper()
+		var req *http.Request
+		if form != nil {
+			req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, path, nil)
+		}
+		req.Header.Set("X-Forwarded-Proto", proto)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		t.Int(method+" "+path+" status", resp.StatusCode, expectedStatus)
+		for _, c := range resp.Cookies() {
+			if c.Name == "session" {
+				cookie = c
+			}
+		}
+		return string(b), resp.Header.Get("Location")
+	}
+
+	_, location := request("GET", "/html/admins", nil, 303)
+	t.String("redirect to sign in", location, "/html/sign-in")
+
+	body, _ := request("GET", "/html/sign-in", nil, 200)
+	t.Bool("sign in form", strings.Contains(body, `name="Password"`), true)
+
+	body, _ = request("POST", "/html/sign-in", url.Values{"Name": {name}, "Password": {"wrong-password"}}, 400)
+	t.Bool("wrong password", strings.Contains(body, "is wrong"), true)
+	t.Bool("name kept", strings.Contains(body, `value="`+name+`"`), true)
+
+	_, location = request("POST", "/html/sign-in", url.Values{"Name": {name}, "Password": {password}}, 303)
+	t.String("redirect to admins", location, "/html/admins")
+	t.Bool("session cookie", cookie != nil && cookie.Value != "" && cookie.HttpOnly && cookie.Secure, true)
+
+	body, _ = request("GET", "/html/admins", nil, 200)
+	t.Bool("admin listed", strings.Contains(body, "<td>"+name+"</td>"), true)
+	t.Bool("sortable", strings.Contains(body, "sort=name"), true)
+	match := regexp.MustCompile(`name="CSRFToken" value="([0-9a-f]+)"`).FindStringSubmatch(body)
+	if match == nil {
+		t.Fatal("no CSRF token")
+	}
+	csrf := match[1]
+
+	request("POST", "/html/admins", url.Values{"Name": {"foo"}, "Password": {"123456"}}, 403)
+	request("POST", "/html/admins", url.Values{"Name": {"foo"}, "Password": {"123456"}, "CSRFToken": {"wrong"}}, 403)
+
+	body, _ = request("POST", "/html/admins", url.Values{"Name": {""}, "Password": {"123456"}, "CSRFToken": {csrf}}, 400)
+	t.Bool("inline error", strings.Contains(body, `<div class="error">length must be greater than 0</div>`), true)
+
+	_, location = request("POST", "/html/admins", url.Values{"Name": {"foo"}, "Password": {"123456"}, "CSRFToken": {csrf}}, 303)
+	t.String("created", location, "/html/admins")
+
+	body, _ = request("GET", "/html/admins?query=foo", nil, 200)
+	t.Bool("search found", strings.Contains(body, "<td>foo</td>"), true)
+	t.Bool("search filtered", strings.Contains(body, "<td>"+name+"</td>"), false)
+
+	body, _ = request("GET", "/html/admins/2", nil, 200)
+	t.Bool("edit form", strings.Contains(body, `value="foo"`), true)
+	t.Bool("password hidden", strings.Contains(body, `type="password" name="Password" value=""`), true)
+
+	_, location = request("POST", "/html/admins/2", url.Values{"Name": {"bar"}, "Password": {""}, "CSRFToken": {csrf}}, 303)
+	t.String("updated", location, "/html/admins")
+	var token tokenResponse
+	t.Request(httptest.NewRequest("POST", "/sign-in", asJson(struct {
+		Name     string
+		Password string
+	}{"bar", "123456"})), 200, &token)
+
+	request("POST", "/html/admins/2/destroy", url.Values{}, 403)
+	_, location = request("POST", "/html/admins/2/destroy", url.Values{"CSRFToken": {csrf}}, 303)
+	t.String("destroyed", location, "/html/admins")
+
+	request("POST", "/html/sign-out", url.Values{}, 403)
+	_, location = request("POST", "/html/sign-out", url.Values{"CSRFToken": {csrf}}, 303)
+	t.String("signed out", location, "/html/sign-in")
+	t.Bool("cookie cleared", cookie.Value == "", true)
+	cookie = nil
+	request("GET", "/html/admins", nil, 303)
+
+	proto = "http"
+	request("POST", "/html/sign-in", url.Values{"Name": {name}, "Password": {password}}, 303)
+	t.Bool("session cookie over http", cookie != nil && cookie.Value != "" && !cookie.Secure, true)
+
+	body, _ = request("GET", "/html/admins", nil, 200)
+	t.Bool("CSRF token of session", strings.Contains(body, csrf), false)
+}
@@ -0,0 +1,177 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestModels(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testModels(t)
+	})
+}
+
+func testModels(t *test) {
+	token := t.signIn()
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("POST", "/posts", strings.NewReader(`{ "Title": "" }`)), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Post.Title","Name":"Title","Kind":"string","Type":"gt","Param":"0"}]}`)
+
+	var post Post
+	t.Request(httptest.NewRequest("POST", "/posts", strings.NewReader(`{ "Title": "Hello", "Views": 10 }`)), 200, &post, token)
+	t.Int("post id", post.Id, 1)
+	t.String("post title", post.Title, "Hello")
+
+	t.Request(httptest.NewRequest("PUT", "/posts/1", strings.NewReader(`{ "Title": "Hello, World", "Views": 20 }`)), 200, &post, token)
+	t.String("post title", post.Title, "Hello, World")
+	t.Int("post views", post.Views, 20)
+
+	var list struct {
+		Records []Post
+	}
+	t.Request(httptest.NewRequest("GET", "/posts?filter%5Bviews%5D%5Bgte%5D=20", nil), 200, &list, token)
+	t.Int("list size", len(list.Records), 1)
+
+	t.Request(httptest.NewRequest("DELETE", "/posts/1", nil), 200, &post, token)
+	t.Request(httptest.NewRequest("GET", "/posts", nil), 200, &list, token)
+	t.Int("list size", len(list.Records), 0)
+}
+
+func TestModelsHooks(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testModelsHooks(t)
+	})
+}
+
+func testModelsHooks(t *test) {
+	token := t.signIn()
+
+	committed := postsCommitted
+
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("POST", "/posts", strings.NewReader(`{ "Title": "   " }`)), 400, &resBody, token)
+	t.Int("posts committed", postsCommitted, committed)
+
+	var post Post
+	t.Request(httptest.NewRequest("POST", "/posts", strings.NewReader(`{ "Title": "  Hello  ", "Views": 1000 }`)), 200, &post, token)
+	t.String("post title", post.Title, "Hello")
+	t.Int("posts committed", postsCommitted, committed+1)
+
+	t.Request(httptest.NewRequest("PATCH", "/posts/1", strings.NewReader(`{ "Title": " Hi " }`)), 200, &post, token)
+	t.String("post title", post.Title, "Hi")
+	t.Int("posts committed", postsCommitted, committed+2)
+
+	t.Request(httptest.NewRequest("DELETE", "/posts/1", nil), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Views","Name":"Views","Kind":"string","Type":"popular","Param":""}]}`)
+	t.Int("posts committed", postsCommitted, committed+2)
+	t.Request(httptest.NewRequest("GET", "/posts/1", nil), 200, &post, token)
+
+	t.Request(httptest.NewRequest("PATCH", "/posts/1", strings.NewReader(`{ "Views": 0 }`)), 200, &post, token)
+	t.Request(httptest.NewRequest("DELETE", "/posts/1", nil), 200, &post, token)
+	t.Int("posts committed", postsCommitted, committed+4)
+	t.Request(httptest.NewRequest("GET", "/posts/1", nil), 404, nil, token)
+}
+
+func TestModelsImport(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testModelsImport(t)
+	})
+}
+
+func testModelsImport(t *test) {
+	token := t.signIn()
+
+	csv := "title,views\nfoo,1\n,2\nbar,3\n"
+
+	var result backend.ImportResult
+	req := httptest.NewRequest("POST", "/posts/import?dry_run=1", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	t.Request(req, 200, &result, token)
+	t.Int("records", len(result.Records), 2)
+	t.Int("errors", len(result.Errors), 1)
+	t.Int("error index", result.Errors[0].Index, 1)
+	t.String("error type", result.Errors[0].Type, "gt")
+
+	var list struct {
+		Records []Post
+	}
+	t.Request(httptest.NewRequest("GET", "/posts", nil), 200, &list, token)
+	t.Int("list size", len(list.Records), 0)
+
+	req = httptest.NewRequest("POST", "/posts/import", strings.NewReader(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	t.Request(req, 200, &result, token)
+	t.Int("inserted", len(result.Ids), 2)
+
+	t.Request(httptest.NewRequest("POST", "/posts/import", strings.NewReader(`[{ "Title": "baz", "Views": 4 }]`)), 200, &result, token)
+	t.Int("inserted", len(result.Ids), 1)
+
+	t.Request(httptest.NewRequest("GET", "/posts?sort=views", nil), 200, &list, token)
+	t.Int("list size", len(list.Records), 3)
+	t.String("post title", list.Records[0].Title, "foo")
+	t.String("post title", list.Records[2].Title, "baz")
+
+	t.Request(httptest.NewRequest("POST", "/admins/import", strings.NewReader(`[{ "Name": "foo", "Password": "123123" }, { "Name": "admin", "Password": "123123" }]`)), 200, &result, token)
+	t.Int("inserted", len(result.Ids), 1)
+	t.Int("errors", len(result.Errors), 1)
+	t.String("error type", result.Errors[0].Type, "uniqueness")
+
+	t.Request(httptest.NewRequest("POST", "/admins/import", strings.NewReader(`[{ "Name": "qux", "Password": "123123" }, { "Name": "qux", "Password": "123123" }]`)), 200, &result, token)
+	t.Int("inserted", len(result.Ids), 1)
+	t.Int("errors", len(result.Errors), 1)
+	t.Int("error index", result.Errors[0].Index, 1)
+	t.String("error type", result.Errors[0].Type, "unique")
+
+	t.Request(httptest.NewRequest("POST", "/admins/import", strings.NewReader(`[{ "Name": "Quux", "Password": "123123" }, { "Name": "quux", "Password": "123123" }]`)), 200, &result, token)
+	t.Int("inserted", len(result.Ids), 1)
+	t.Int("errors", len(result.Errors), 1)
+	t.Int("error index", result.Errors[0].Index, 1)
+	t.String("error type", result.Errors[0].Type, "unique")
+}
+
+func TestModelsPurge(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testModelsPurge(t)
+	})
+}
+
+func testModelsPurge(t *test) {
+	posts := backend.Default.ModelByName("Post")
+	posts.Insert("Title", "foo").MustExecute()
+	posts.Insert("Title", "bar").MustExecute()
+	comments := backend.Default.ModelByName("Comment")
+	comments.Insert("PostId", 1, "Body", "first").MustExecute()
+	comments.Insert("ParentId", 1, "Body", "reply").MustExecute()
+	comments.Insert("ParentId", 2, "Body", "reply of reply").MustExecute()
+	comments.Insert("PostId", 2, "Body", "second").MustExecute()
+	comments.Insert("ParentId", 5, "Body", "reply of itself").MustExecute()
+
+	if err := backend.Default.DeletePermanently(posts, 1); err != nil {
+		t.Fatal(err)
+	}
+	var rest []Comment
+	if err := comments.Find().OrderBy("id").Query(&rest); err != nil {
+		t.Fatal(err)
+	}
+	t.Int("comments", len(rest), 2)
+	t.String("comment", rest[0].Body, "second")
+
+	if err := backend.Default.DeletePermanently(comments, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := comments.Find().Query(&rest); err != nil {
+		t.Fatal(err)
+	}
+	t.Int("comments", len(rest), 1)
+}
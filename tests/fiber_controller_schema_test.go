@@ -0,0 +1,60 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestSchemas(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testSchemas(t)
+	})
+}
+
+func testSchemas(t *test) {
+	token := t.signIn()
+
+	t.Request(httptest.NewRequest("GET", "/schemas/Admin", nil), 401, nil)
+	t.Request(httptest.NewRequest("GET", "/schemas/Unknown", nil), 404, nil, token)
+
+	var admin struct {
+		Name   string
+		Schema json.RawMessage
+		Params map[string][]string
+	}
+	t.Request(httptest.NewRequest("GET", "/schemas/Admin", nil), 200, &admin, token)
+	t.String("name", admin.Name, "Admin")
+	t.String("schema", string(admin.Schema), `{"$schema":"https://json-schema.org/draft/2020-12/schema",`+
+		`"properties":{"CreatedAt":{"format":"date-time","type":"string"},`+
+		`"DeletedAt":{"format":"date-time","type":["string","null"]},"Id":{"type":"integer"},`+
+		`"Name":{"maxLength":30,"minLength":1,"type":"string"},`+
+		`"Password":{"format":"password","type":"string","writeOnly":true},`+
+		`"UpdatedAt":{"format":"date-time","type":"string"}},`+
+		`"required":["Password"],"title":"Admin","type":"object"}`)
+	t.String("create params", strings.Join(admin.Params["create"], ","), "Name,Password")
+
+	var list struct {
+		Schemas []struct {
+			Name   string
+			Params map[string][]string
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/schemas", nil), 200, &list, token)
+	var names []string
+	for _, s := range list.Schemas {
+		names = append(names, s.Name)
+		if s.Name == "Post" {
+			t.String("update params", strings.Join(s.Params["update"], ","), "Title,Views")
+		}
+	}
+	t.String("names", strings.Join(names, ","), "Admin,AdminSession,IdempotencyKey,Tenant,TenantMembership,Post,Note,Comment")
+
+	post, _ := backend.Default.ModelSchema("Post")
+	views := post.Schema["properties"].(map[string]interface{})["Views"].(map[string]interface{})
+	t.Bool("views minimum", views["minimum"] == float64(0), true)
+}
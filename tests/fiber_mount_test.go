@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gopsql/backend"
+)
+
+func TestMountFiber(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testMountFiber(t)
+	})
+}
+
+func testMountFiber(t *test) {
+	api := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if e, ok := err.(*fiber.Error); ok {
+				return c.SendStatus(e.Code)
+			}
+			status, content := backend.Default.HandleError(err)
+			return c.Status(status).JSON(content)
+		},
+	})
+	err := backend.Default.MountFiber(api, backend.FiberMountOptions{
+		Prefix:     "/api",
+		AdminsPath: "/users",
+		Disabled:   []string{"Admin.Purge", "Admin.Sessions"},
+		Handlers: map[string]backend.FiberHandler{
+			"Session.Me": func(c backend.FiberCtx) error {
+				return c.JSON("me")
+			},
+		},
+		Middlewares: []backend.FiberHandler{
+			func(c backend.FiberCtx) error {
+				c.Set("X-Authenticated", "1")
+				return c.Next()
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Bool("invalid router", backend.Default.MountFiber(struct{}{}, backend.FiberMountOptions{}) != nil, true)
+
+	defer func(a *fiber.App) { app = a }(app)
+	app = api
+
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+
+	var token tokenResponse
+	t.Request(httptest.NewRequest("POST", "/api/sign-in", asJson(struct {
+		Name     string
+		Password string
+	}{name, password})), 200, &token)
+
+	t.String("me", t.RequestBody(httptest.NewRequest("GET", "/api/me", nil), 200), `"me"`)
+
+	t.Request(httptest.NewRequest("GET", "/api/users", nil), 401, nil)
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Authorization", token.Token)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("status", resp.StatusCode, 200)
+	t.String("middleware header", resp.Header.Get("X-Authenticated"), "1")
+
+	t.Request(httptest.NewRequest("GET", "/api/users/1", nil), 200, nil, token)
+	t.Request(httptest.NewRequest("DELETE", "/api/users/1/permanently", nil), 404, nil, token)
+	t.Request(httptest.NewRequest("GET", "/api/users/1/sessions", nil), 405, nil, token)
+	t.Request(httptest.NewRequest("GET", "/api/posts", nil), 404, nil, token)
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 404, nil, token)
+
+	// optional routes are not registered unless enabled
+	for _, path := range []string{"/api/openapi.json", "/api/ui", "/api/html/sign-in", "/api/schemas", "/api/graphql"} {
+		t.Request(httptest.NewRequest("GET", path, nil), 404, nil, token)
+	}
+
+	// errors and panics are responded with HandleError without ErrorHandler
+	app = fiber.New()
+	backend.Default.MustMountFiber(app, backend.FiberMountOptions{
+		Handlers: map[string]backend.FiberHandler{
+			"Session.Me": func(c backend.FiberCtx) error {
+				panic(backend.NewInputErrors("Name", "required"))
+			},
+		},
+	})
+	var resBody json.RawMessage
+	t.Request(httptest.NewRequest("POST", "/admins", strings.NewReader(`{ "Name": "" }`)), 400, &resBody, token)
+	t.String("response", string(resBody),
+		`{"Errors":[{"FullName":"Admin.Name","Name":"Name","Kind":"string","Type":"gt","Param":"0"}]}`)
+	t.Request(httptest.NewRequest("GET", "/admins/100", nil), 404, nil, token)
+	t.Request(httptest.NewRequest("GET", "/me", nil), 400, nil)
+}
@@ -6,22 +6,85 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gopsql/backend"
 	"github.com/gopsql/jwt"
 	"github.com/gopsql/logger"
+	"github.com/gopsql/psql"
 	"github.com/gopsql/sqlite"
 )
 
-var app *fiber.App
+var (
+	// routes registered by hand, like in the README
+	app *fiber.App
+
+	// routes registered by MustMountFiber()
+	mountedApp *fiber.App
+)
+
+type Post struct {
+	Id        int
+	Title     string `validate:"gt=0,lte=100"`
+	Views     int    `validate:"gte=0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Post) Filters() map[string][]string {
+	return map[string][]string{
+		"Title": {"eq", "like"},
+		"Views": {"gt", "gte", "lt", "lte"},
+	}
+}
+
+// number of posts committed, counted by AfterCommit
+var postsCommitted int
+
+func (p *Post) BeforeValidate(tx *psql.Tx) error {
+	p.Title = strings.TrimSpace(p.Title)
+	return nil
+}
+
+func (p *Post) BeforeDestroy(tx *psql.Tx) error {
+	if p.Views >= 1000 {
+		return backend.NewInputErrors("Views", "popular")
+	}
+	return nil
+}
+
+func (p *Post) AfterCommit() {
+	postsCommitted++
+}
+
+// Comment depends on Post by PostId and on its parent comment.
+type Comment struct {
+	Id       int
+	PostId   int
+	ParentId int
+	Body     string
+}
+
+func (Comment) Dependents() map[string]string {
+	return map[string]string{
+		"Comment": "ParentId",
+	}
+}
 
 func init() {
 	backend.Default.AddModelAdmin()
 	backend.Default.AddModelAdminSession()
+	backend.Default.AddModelIdempotencyKey()
+	backend.Default.AddModelTenant()
+	backend.Default.NewModel(Post{})
+	backend.Default.NewModel(Note{})
+	backend.Default.NewModel(Comment{})
 
 	var l logger.Logger
 	if os.Getenv("DEBUG") == "1" {
@@ -36,13 +99,7 @@ func init() {
 		SessionIdKeyName: "SessionId",
 	}))
 
-	app = fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			status, content := backend.Default.HandleError(err)
-			return c.Status(status).JSON(content)
-		},
-	})
-	app.Use(recover.New())
+	app = newApp()
 
 	sc := backend.Default.NewFiberSessionsCtrl()
 	app.Post("/sign-in", wrap(sc.SignIn))
@@ -53,11 +110,56 @@ func init() {
 
 	ac := backend.Default.NewFiberAdminsCtrl()
 	app.Get("/admins", wrap(ac.List))
+	app.Get("/admins/export.:format", wrap(ac.Export))
+	app.Post("/admins/import", wrap(ac.Import))
+	app.Post("/admins/bulk/update", wrap(ac.BulkUpdate))
+	app.Post("/admins/bulk/destroy", wrap(ac.BulkDestroy))
+	app.Post("/admins/bulk/restore", wrap(ac.BulkRestore))
 	app.Get("/admins/:id", wrap(ac.Show))
 	app.Post("/admins", wrap(ac.Create))
 	app.Put("/admins/:id", wrap(ac.Update))
+	app.Patch("/admins/:id", wrap(ac.Update))
 	app.Delete("/admins/:id", wrap(ac.Destroy))
+	app.Delete("/admins/:id/permanently", wrap(ac.Purge))
 	app.Post("/admins/:id", wrap(ac.Restore))
+	app.Get("/admins/:id/sessions", wrap(ac.Sessions))
+	app.Delete("/admins/:id/sessions", wrap(ac.RevokeSessions))
+	app.Delete("/admins/:id/sessions/:sessionId", wrap(ac.RevokeSession))
+
+	schc := backend.Default.NewFiberSchemasCtrl()
+	app.Get("/schemas", wrap(schc.List))
+	app.Get("/schemas/:name", wrap(schc.Show))
+
+	pc := backend.Default.NewFiberModelsCtrl("Post")
+	app.Get("/posts", wrap(pc.List))
+	app.Get("/posts/export.:format", wrap(pc.Export))
+	app.Post("/posts/import", wrap(pc.Import))
+	app.Get("/posts/:id", wrap(pc.Show))
+	app.Post("/posts", wrap(pc.Create))
+	app.Put("/posts/:id", wrap(pc.Update))
+	app.Patch("/posts/:id", wrap(pc.Update))
+	app.Delete("/posts/:id", wrap(pc.Destroy))
+
+	mountedApp = newApp()
+	backend.Default.MustMountFiber(mountedApp, backend.FiberMountOptions{
+		Models:  map[string]string{"Post": "/posts"},
+		OpenAPI: true,
+		Schemas: true,
+		UI:      true,
+		HTML:    true,
+		GraphQL: true,
+	})
+}
+
+func newApp() *fiber.App {
+	a := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			status, content := backend.Default.HandleError(err)
+			return c.Status(status).JSON(content)
+		},
+	})
+	a.Use(recover.New())
+	return a
 }
 
 func wrap(f backend.FiberHandler) fiber.Handler {
@@ -82,6 +184,13 @@ func testWithSqlite(test func()) {
 	test()
 }
 
+// testWithMountedApp runs test with the routes registered by MustMountFiber().
+func testWithMountedApp(test func()) {
+	defer func(a *fiber.App) { app = a }(app)
+	app = mountedApp
+	test()
+}
+
 func asJson(v interface{}) io.Reader {
 	var buf bytes.Buffer
 	json.NewEncoder(&buf).Encode(v)
@@ -94,8 +203,31 @@ type tokenResponse struct {
 	Token string
 }
 
+// signIn creates the admin named admin, or resets its password, and signs in
+// as it.
+func (t *test) signIn() (token tokenResponse) {
+	t.Helper()
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+	t.Request(httptest.NewRequest("POST", "/sign-in", asJson(struct {
+		Name     string
+		Password string
+	}{name, password})), 200, &token)
+	return
+}
+
+// createAdmins creates admins with given names and password 123123.
+func (t *test) createAdmins(token tokenResponse, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Request(httptest.NewRequest("POST", "/admins", asJson(struct {
+			Name     string
+			Password string
+		}{name, "123123"})), 200, nil, token)
+	}
+}
+
 func (t *test) Request(req *http.Request, expectedStatus int, v interface{}, extras ...interface{}) {
-	if req.Method == "POST" {
+	if req.Method == "POST" && req.Header.Get("Content-Type") == "" {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	for _, extra := range extras {
@@ -120,6 +252,28 @@ func (t *test) Request(req *http.Request, expectedStatus int, v interface{}, ext
 	}
 }
 
+func (t *test) RequestBody(req *http.Request, expectedStatus int, extras ...interface{}) string {
+	for _, extra := range extras {
+		switch e := extra.(type) {
+		case tokenResponse:
+			req.Header.Set("Authorization", e.Token)
+		}
+	}
+	t.Helper()
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	t.Int(fmt.Sprintf("%s %s status code", req.Method, req.URL.RequestURI()), resp.StatusCode, expectedStatus)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	return string(b)
+}
+
 func (t *test) Bool(name string, got, expected bool) {
 	t.Helper()
 	if got == expected {
@@ -0,0 +1,88 @@
+package backend
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestForm(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testForm(t)
+	})
+}
+
+func testForm(t *test) {
+	token := t.signIn()
+
+	form := func(values url.Values) *strings.Reader {
+		return strings.NewReader(values.Encode())
+	}
+
+	var post Post
+	req := httptest.NewRequest("POST", "/posts", form(url.Values{"title": {"hello"}, "Views": {"3"}, "Id": {"100"}}))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	t.Request(req, 200, &post, token)
+	t.Int("post id", post.Id, 1)
+	t.String("post title", post.Title, "hello")
+	t.Int("post views", post.Views, 3)
+
+	var errs struct {
+		Errors []backend.InputError
+	}
+	req = httptest.NewRequest("POST", "/posts", form(url.Values{"Title": {"hello"}, "Views": {"many"}}))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	t.Request(req, 400, &errs, token)
+	t.Int("errors", len(errs.Errors), 1)
+	t.String("error name", errs.Errors[0].Name, "Views")
+	t.String("error type", errs.Errors[0].Type, "invalid")
+
+	multipartBody := func(fields ...string) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for i := 0; i < len(fields); i += 2 {
+			w.WriteField(fields[i], fields[i+1])
+		}
+		w.Close()
+		return &buf, w.FormDataContentType()
+	}
+
+	body, ctype := multipartBody("Views", "7")
+	req = httptest.NewRequest("PATCH", "/posts/1", body)
+	req.Header.Set("Content-Type", ctype)
+	t.Request(req, 200, &post, token)
+	t.String("post title", post.Title, "hello")
+	t.Int("post views", post.Views, 7)
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	fw, _ := w.CreateFormFile("Title", "title.txt")
+	fw.Write([]byte("from file"))
+	w.Close()
+	req = httptest.NewRequest("PUT", "/posts/1", &buf)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	t.Request(req, 200, &post, token)
+	t.String("post title", post.Title, "from file")
+	t.Int("post views", post.Views, 0)
+
+	var admin struct {
+		Id   int
+		Name string
+	}
+	body, ctype = multipartBody("Name", "editor", "Password", "secret")
+	req = httptest.NewRequest("POST", "/admins", body)
+	req.Header.Set("Content-Type", ctype)
+	t.Request(req, 200, &admin, token)
+	t.String("admin name", admin.Name, "editor")
+
+	req = httptest.NewRequest("POST", "/posts", strings.NewReader("Title=%zz"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	t.Request(req, 400, &errs, token)
+	t.String("error name", errs.Errors[0].Name, "Body")
+}
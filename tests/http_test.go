@@ -0,0 +1,111 @@
+package backend
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestHTTP(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testHTTP(t)
+	})
+}
+
+func testHTTP(t *test) {
+	b := backend.Default
+	sc := b.NewFiberSessionsCtrl()
+	ac := b.NewFiberAdminsCtrl()
+	params := func(r *http.Request, key string) string {
+		if key == "id" {
+			return strings.TrimPrefix(r.URL.Path, "/admins/")
+		}
+		return ""
+	}
+	authenticate := b.NewHTTPMiddleware(params, sc.Authenticate)
+	methods := func(handlers map[string]http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h, ok := handlers[r.Method]; ok {
+				h.ServeHTTP(w, r)
+				return
+			}
+			w.WriteHeader(405)
+		})
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/sign-in", b.NewHTTPHandler(nil, sc.SignIn))
+	mux.Handle("/me", b.NewHTTPHandler(nil, sc.Me))
+	mux.Handle("/admins", authenticate(methods(map[string]http.Handler{
+		"GET":  b.NewHTTPHandler(params, ac.List),
+		"POST": b.NewHTTPHandler(params, ac.Create),
+	})))
+	mux.Handle("/admins/", methods(map[string]http.Handler{
+		"GET":   b.NewHTTPHandler(params, sc.Authenticate, ac.Show),
+		"PATCH": b.NewHTTPHandler(params, sc.Authenticate, ac.Update),
+	}))
+
+	request := func(method, path, body, token string, expectedStatus int, v interface{}) http.Header {
+		t.Helper()
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req := httptest.NewRequest(method, path, r)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		t.Int(method+" "+path+" status", w.Code, expectedStatus)
+		if v != nil {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Header()
+	}
+
+	name, password, _ := b.CreateAdmin("admin", "")
+	var token tokenResponse
+	request("POST", "/sign-in", `{"Name":"`+name+`","Password":"wrong-password"}`, "", 400, nil)
+	request("POST", "/sign-in", `{"Name":"`+name+`","Password":"`+password+`"}`, "", 200, &token)
+
+	var me struct{ Name string }
+	request("GET", "/me", "", token.Token, 200, &me)
+	t.String("me", me.Name, name)
+
+	var message struct{ Message string }
+	request("GET", "/admins", "", "", 401, &message)
+	t.String("message", message.Message, "Please Log In")
+
+	var list struct {
+		Admins []struct{ Name string }
+	}
+	request("GET", "/admins?query=adm", "", token.Token, 200, &list)
+	t.Int("admins", len(list.Admins), 1)
+
+	var admin struct {
+		Id   int
+		Name string
+	}
+	request("POST", "/admins", `{"Name":"foo","Password":"123456"}`, token.Token, 200, &admin)
+	t.String("created", admin.Name, "foo")
+
+	header := request("GET", "/admins/2", "", token.Token, 200, &admin)
+	t.Bool("etag", header.Get("ETag") != "", true)
+	request("GET", "/admins/99", "", token.Token, 404, nil)
+	request("GET", "/admins/2", "", "", 401, nil)
+
+	var errs struct{ Errors []struct{ Name, Type string } }
+	request("PATCH", "/admins/2", `{"Name":""}`, token.Token, 400, &errs)
+	t.String("error", errs.Errors[0].Type, "gt")
+	request("PATCH", "/admins/2", `{"Name":"bar"}`, token.Token, 200, &admin)
+	t.String("updated", admin.Name, "bar")
+}
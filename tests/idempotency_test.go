@@ -0,0 +1,121 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gopsql/backend"
+)
+
+func TestIdempotency(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testIdempotency(t)
+		})
+	})
+}
+
+func testIdempotency(t *test) {
+	token := t.signIn()
+
+	var post Post
+	req := httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "hello"}))
+	req.Header.Set("Idempotency-Key", "key-1")
+	t.Request(req, 200, &post, token)
+	t.Int("post id", post.Id, 1)
+
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "hello"}))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", token.Token)
+	req.Header.Set("Idempotency-Key", "key-1")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("replay status", resp.StatusCode, 200)
+	t.String("replayed", resp.Header.Get("Idempotent-Replayed"), "true")
+	var replayed Post
+	json.NewDecoder(resp.Body).Decode(&replayed)
+	resp.Body.Close()
+	t.Int("replayed post id", replayed.Id, 1)
+	t.String("replayed post title", replayed.Title, "hello")
+
+	var list struct {
+		Records []Post
+	}
+	t.Request(httptest.NewRequest("GET", "/posts", nil), 200, &list, token)
+	t.Int("posts", len(list.Records), 1)
+
+	var errs struct {
+		Errors []backend.InputError
+	}
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "world"}))
+	req.Header.Set("Idempotency-Key", "key-1")
+	t.Request(req, 400, &errs, token)
+	t.String("conflict name", errs.Errors[0].Name, "Idempotency-Key")
+	t.String("conflict type", errs.Errors[0].Type, "conflict")
+
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": ""}))
+	req.Header.Set("Idempotency-Key", "key-2")
+	t.Request(req, 400, &errs, token)
+	t.String("error name", errs.Errors[0].Name, "Title")
+
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "world"}))
+	req.Header.Set("Idempotency-Key", "key-2")
+	t.Request(req, 200, &post, token)
+	t.Int("retried post id", post.Id, 2)
+
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "again"}))
+	t.Request(req, 200, &post, token)
+	t.Int("post without key id", post.Id, 3)
+
+	// boundary of multipart forms is different every time
+	multipartForm := func(title string) *http.Request {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		w.WriteField("Title", title)
+		w.Close()
+		req := httptest.NewRequest("POST", "/posts", &buf)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		req.Header.Set("Idempotency-Key", "key-3")
+		return req
+	}
+	t.Request(multipartForm("form"), 200, &post, token)
+	t.Int("form post id", post.Id, 4)
+	t.Request(multipartForm("form"), 200, &post, token)
+	t.Int("retried form post id", post.Id, 4)
+	t.Request(multipartForm("changed"), 400, &errs, token)
+	t.String("form conflict type", errs.Errors[0].Type, "conflict")
+
+	// keys of requests in progress are released after the lock timeout
+	keys := backend.Default.ModelByName("IdempotencyKey")
+	now := time.Now().UTC()
+	for key, lockedAt := range map[string]time.Time{"key-4": now, "key-5": now.Add(-time.Hour)} {
+		keys.Insert("AdminId", 1, "Key", key, "RequestHash", "", "LockedAt", lockedAt,
+			"CreatedAt", now, "UpdatedAt", now).MustExecute()
+	}
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "locked"}))
+	req.Header.Set("Idempotency-Key", "key-4")
+	t.Request(req, 400, &errs, token)
+	t.String("locked key", errs.Errors[0].Name, "Idempotency-Key")
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "stale"}))
+	req.Header.Set("Idempotency-Key", "key-5")
+	t.Request(req, 200, &post, token)
+	t.Int("stale key post id", post.Id, 5)
+
+	backend.Default.SetIdempotencyPeriod(0)
+	defer backend.Default.SetIdempotencyPeriod(backend.DefaultIdempotencyPeriod)
+	if err := backend.Default.DeleteExpiredIdempotencyKeys(); err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{"Title": "hello"}))
+	req.Header.Set("Idempotency-Key", "key-1")
+	t.Request(req, 200, &post, token)
+	t.Int("expired key post id", post.Id, 6)
+}
@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gopsql/backend"
+)
+
+func TestKeyCase(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		backend.Default.SetKeyCase(backend.KeyCaseSnake)
+		defer backend.Default.SetKeyCase(backend.KeyCaseGo)
+		testWithMountedApp(func() {
+			testKeyCase(t)
+		})
+	})
+}
+
+func testKeyCase(t *test) {
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+
+	var token tokenResponse
+	t.Request(httptest.NewRequest("POST", "/sign-in", asJson(map[string]string{
+		"name":     name,
+		"password": password,
+	})), 200, &token)
+	t.Bool("token", token.Token != "", true)
+
+	out := t.RequestBody(httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{
+		"title": "hello",
+		"views": 3,
+	})), 200, token)
+	t.Bool("create", strings.HasPrefix(out, `{"id":1,"title":"hello","views":3,"created_at":`), true)
+
+	out = t.RequestBody(httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{
+		"title": "",
+	})), 400, token)
+	t.Bool("input errors", strings.HasPrefix(out, `{"errors":[{"full_name":"post.title","name":"title",`), true)
+
+	t.RequestBody(httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{
+		"title": "world",
+		"views": 5,
+	})), 200, token)
+
+	out = t.RequestBody(httptest.NewRequest("GET", "/posts?sort=views&order=desc&filter[views][gte]=1&fields=id,created_at", nil), 200, token)
+	t.Bool("list", strings.HasPrefix(out, `{"records":[{"id":2,"created_at":`), true)
+	t.Bool("pagination", strings.Contains(out, `"pagination":{`), true)
+
+	out = t.RequestBody(httptest.NewRequest("GET", "/posts?filter[created_at]=1", nil), 400, token)
+	t.Bool("filter errors", strings.Contains(out, `"name":"created_at","kind":"string","type":"filter"`), true)
+
+	t.String("not found", t.RequestBody(httptest.NewRequest("GET", "/posts/100", nil), 404, token), `{"message":"Not Found"}`)
+
+	backend.Default.SetKeyCase(backend.KeyCaseCamel)
+
+	out = t.RequestBody(httptest.NewRequest("PATCH", "/posts/1", asJson(map[string]interface{}{
+		"views": 10,
+	})), 200, token)
+	t.Bool("update", strings.Contains(out, `"views":10,"createdAt":`), true)
+
+	var schema struct {
+		Name   string
+		Schema struct {
+			Properties map[string]interface{}
+		}
+		Params map[string][]string
+	}
+	t.Request(httptest.NewRequest("GET", "/schemas/Post", nil), 200, &schema, token)
+	t.String("schema name", schema.Name, "Post")
+	t.Bool("schema properties", schema.Schema.Properties["createdAt"] != nil, true)
+	t.String("schema params", strings.Join(schema.Params["create"], ","), "title,views")
+
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{}
+			}
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/openapi.json", nil), 200, &doc)
+	t.Bool("openapi properties", doc.Components.Schemas["Post"].Properties["createdAt"] != nil, true)
+	t.Bool("openapi input error", doc.Components.Schemas["InputError"].Properties["fullName"] != nil, true)
+
+	var ret graphQLResponse
+	t.Request(httptest.NewRequest("POST", "/graphql", asJson(map[string]interface{}{
+		"query":     `query ($views: Int) { posts(filter: {views: {gte: $views}}) { records { id createdAt } } }`,
+		"variables": map[string]interface{}{"views": 6},
+	})), 200, &ret, token)
+	t.Int("graphql errors", len(ret.Errors), 0)
+	t.Bool("graphql", strings.HasPrefix(string(ret.Data["posts"]), `{"records":[{"id":1,"createdAt":`), true)
+}
+
+func TestKeyCaseManualRoutes(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		backend.Default.SetKeyCase(backend.KeyCaseSnake)
+		defer backend.Default.SetKeyCase(backend.KeyCaseGo)
+		defer func(a *fiber.App) { app = a }(app)
+		app = newApp()
+		// like convert() of the README
+		negotiated := func(f backend.FiberHandler) fiber.Handler {
+			h := backend.Default.FiberNegotiate(f)
+			return func(c *fiber.Ctx) error {
+				return h(c)
+			}
+		}
+		sc := backend.Default.NewFiberSessionsCtrl()
+		app.Post("/sign-in", negotiated(sc.SignIn))
+		app.Use(negotiated(sc.Authenticate))
+		pc := backend.Default.NewFiberModelsCtrl("Post")
+		app.Get("/posts/:id", negotiated(pc.Show))
+		app.Post("/posts", negotiated(pc.Create))
+		testKeyCaseManualRoutes(t)
+	})
+}
+
+func testKeyCaseManualRoutes(t *test) {
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+
+	var token tokenResponse
+	t.Request(httptest.NewRequest("POST", "/sign-in", asJson(map[string]string{
+		"name":     name,
+		"password": password,
+	})), 200, &token)
+	t.Bool("token", token.Token != "", true)
+
+	out := t.RequestBody(httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{
+		"title": "hello",
+		"views": 3,
+	})), 200, token)
+	t.Bool("create", strings.HasPrefix(out, `{"id":1,"title":"hello","views":3,"created_at":`), true)
+
+	out = t.RequestBody(httptest.NewRequest("POST", "/posts", asJson(map[string]interface{}{
+		"title": "",
+	})), 400, token)
+	t.Bool("input errors", strings.HasPrefix(out, `{"errors":[{"full_name":"post.title","name":"title",`), true)
+
+	out = t.RequestBody(httptest.NewRequest("GET", "/posts/1", nil), 200, token)
+	t.Bool("show", strings.HasPrefix(out, `{"id":1,"title":"hello"`), true)
+
+	t.String("not found", t.RequestBody(httptest.NewRequest("GET", "/posts/100", nil), 404, token), `{"message":"Not Found"}`)
+}
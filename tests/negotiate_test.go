@@ -0,0 +1,81 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestNegotiate(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testNegotiate(t)
+		})
+	})
+}
+
+// msgpackMap encodes map of short strings in MessagePack.
+func msgpackMap(pairs ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+	buf.WriteByte(0x80 | byte(len(pairs)/2))
+	for _, s := range pairs {
+		buf.WriteByte(0xa0 | byte(len(s)))
+		buf.WriteString(s)
+	}
+	return &buf
+}
+
+func testNegotiate(t *test) {
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+
+	req := httptest.NewRequest("POST", "/sign-in", msgpackMap("Name", name, "Password", password))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("sign in status", resp.StatusCode, 200)
+	t.String("sign in content type", resp.Header.Get("Content-Type"), "application/msgpack")
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	t.String("token key", hex.EncodeToString(body.Bytes()[:7]), "81a5546f6b656e") // {"Token":
+	b := body.Bytes()[7:]
+	switch b[0] { // str8 or str16
+	case 0xd9:
+		b = b[2:]
+	case 0xda:
+		b = b[3:]
+	}
+	token := tokenResponse{string(b)}
+
+	req = httptest.NewRequest("POST", "/posts", msgpackMap("Title", "hello"))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/cbor")
+	out := t.RequestBody(req, 200, token)
+	t.Bool("cbor map", out[0]>>5 == 5, true)
+	t.Bool("cbor title", strings.Contains(out, "\x65Title\x65hello"), true)
+
+	req = httptest.NewRequest("GET", "/posts/100", nil)
+	req.Header.Set("Accept", "application/cbor")
+	t.String("not found", hex.EncodeToString([]byte(t.RequestBody(req, 404, token))),
+		"a1674d65737361676569"+hex.EncodeToString([]byte("Not Found"))) // {"Message":"Not Found"}
+
+	req = httptest.NewRequest("POST", "/posts", msgpackMap("Title", ""))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/json")
+	t.Bool("input errors", strings.HasPrefix(t.RequestBody(req, 400, token), `{"Errors":[`), true)
+
+	req = httptest.NewRequest("POST", "/posts", bytes.NewReader([]byte{0xc1}))
+	req.Header.Set("Content-Type", "application/msgpack")
+	t.Request(req, 400, nil, token)
+
+	var post struct{ Title string }
+	t.Request(httptest.NewRequest("GET", "/posts/1", nil), 200, &post, token)
+	t.String("json", post.Title, "hello")
+}
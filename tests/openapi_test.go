@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gopsql/backend"
+)
+
+func TestOpenAPI(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testOpenAPI(t)
+		})
+	})
+}
+
+// Run with UPDATE_OPENAPI=1 to regenerate testdata/openapi.json.
+func testOpenAPI(t *test) {
+	body := t.RequestBody(httptest.NewRequest("GET", "/openapi.json", nil), 200)
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteByte('\n')
+	if os.Getenv("UPDATE_OPENAPI") != "" {
+		if err := os.WriteFile("testdata/openapi.json", buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile("testdata/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := strings.Split(buf.String(), "\n"), strings.Split(string(expected), "\n")
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if got[i] != want[i] {
+			t.Fatalf("openapi.json line %d: got %q, expected %q", i+1, got[i], want[i])
+		}
+	}
+	t.Int("openapi.json lines", len(got), len(want))
+
+	doc, err := backend.Default.OpenAPI(backend.FiberMountOptions{
+		Title:    "Test",
+		Version:  "2.0.0",
+		Disabled: []string{"Admin", "Session.Me"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.String("title", doc["info"].(map[string]interface{})["title"].(string), "Test")
+	paths := doc["paths"].(map[string]interface{})
+	_, hasAdmins := paths["/admins"]
+	t.Bool("disabled admins", hasAdmins, false)
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	_, hasAdmin := schemas["Admin"]
+	t.Bool("disabled admin schema", hasAdmin, false)
+}
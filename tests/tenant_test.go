@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gopsql/backend"
+)
+
+type Note struct {
+	Id        int
+	TenantId  int
+	Title     string `validate:"gt=0,lte=100,uniqueness"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func TestTenant(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		defer func(a *fiber.App) { app = a }(app)
+		app = fiber.New(fiber.Config{
+			ErrorHandler: func(c *fiber.Ctx, err error) error {
+				status, content := backend.Default.HandleError(err)
+				return c.Status(status).JSON(content)
+			},
+		})
+		backend.Default.MustMountFiber(app, backend.FiberMountOptions{
+			Models: map[string]string{"Note": "/notes"},
+		})
+		testTenant(t)
+	})
+}
+
+func testTenant(t *test) {
+	tenants := backend.Default.ModelByName("Tenant")
+	tenants.Insert("Name", "Acme", "Subdomain", "acme").MustExecute()
+	tenants.Insert("Name", "Globex", "Subdomain", "globex").MustExecute()
+
+	signIn := func(name, password, tenant string, status int) (token tokenResponse) {
+		req := httptest.NewRequest("POST", "/sign-in", asJson(struct {
+			Name     string
+			Password string
+		}{name, password}))
+		req.Header.Set("X-Tenant", tenant)
+		t.Request(req, status, &token)
+		return
+	}
+	name, password, _ := backend.Default.CreateAdmin("admin", "")
+	root := signIn(name, password, "", 200)
+
+	req := httptest.NewRequest("POST", "/admins", asJson(map[string]string{
+		"Name":     "acme-admin",
+		"Password": "123456",
+	}))
+	req.Header.Set("X-Tenant", "acme")
+	t.Request(req, 404, nil, root)
+
+	backend.Default.SetCrossTenantAdmins(true)
+	defer backend.Default.SetCrossTenantAdmins(false)
+
+	var admin struct {
+		Id   int
+		Name string
+	}
+	for _, tenant := range []string{"acme", "globex"} {
+		req := httptest.NewRequest("POST", "/admins", asJson(map[string]string{
+			"Name":     tenant + "-admin",
+			"Password": "123456",
+		}))
+		req.Header.Set("X-Tenant", tenant)
+		t.Request(req, 200, &admin, root)
+	}
+	acme := signIn("acme-admin", "123456", "", 200)
+	globex := signIn("globex-admin", "123456", "globex", 200)
+	signIn("acme-admin", "123456", "globex", 400)
+	signIn("acme-admin", "123456", "initech", 404)
+
+	req = httptest.NewRequest("POST", "/sign-in", asJson(map[string]string{
+		"Name":     "acme-admin",
+		"Password": "123456",
+	}))
+	req.Host = "acme.example.com"
+	t.Request(req, 200, nil)
+
+	req = httptest.NewRequest("POST", "/sign-in", asJson(map[string]string{
+		"Name":     "acme-admin",
+		"Password": "123456",
+	}))
+	req.Host = "api.example.com"
+	t.Request(req, 200, nil)
+
+	var note Note
+	t.Request(httptest.NewRequest("POST", "/notes", asJson(map[string]interface{}{
+		"Title":    "hello",
+		"TenantId": 2,
+	})), 200, &note, acme)
+	t.Int("note id", note.Id, 1)
+	t.Int("note tenant id", note.TenantId, 1)
+
+	t.Request(httptest.NewRequest("POST", "/notes", asJson(map[string]string{"Title": "hello"})), 200, &note, globex)
+	t.Int("note id", note.Id, 2)
+	t.Int("note tenant id", note.TenantId, 2)
+
+	var errs struct {
+		Errors []backend.InputError
+	}
+	t.Request(httptest.NewRequest("POST", "/notes", asJson(map[string]string{"Title": "hello"})), 400, &errs, acme)
+	t.String("error name", errs.Errors[0].Name, "Title")
+	t.String("error type", errs.Errors[0].Type, "uniqueness")
+
+	var notes struct {
+		Records []Note
+	}
+	t.Request(httptest.NewRequest("GET", "/notes", nil), 200, &notes, acme)
+	t.Int("acme notes", len(notes.Records), 1)
+	t.Request(httptest.NewRequest("GET", "/notes/2", nil), 404, nil, acme)
+	t.Request(httptest.NewRequest("PATCH", "/notes/2", asJson(map[string]string{"Title": "world"})), 404, nil, acme)
+
+	req = httptest.NewRequest("GET", "/notes", nil)
+	req.Header.Set("X-Tenant", "globex")
+	t.Request(req, 404, nil, acme)
+
+	t.Request(httptest.NewRequest("GET", "/notes", nil), 400, &errs, root)
+	t.String("error name", errs.Errors[0].Name, "Tenant")
+	t.String("error type", errs.Errors[0].Type, "required")
+
+	req = httptest.NewRequest("GET", "/notes", nil)
+	req.Header.Set("X-Tenant", "globex")
+	t.Request(req, 200, &notes, root)
+	t.Int("globex notes", len(notes.Records), 1)
+	t.Int("globex note id", notes.Records[0].Id, 2)
+
+	var admins struct {
+		Admins []struct {
+			Name string
+		}
+	}
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &admins, acme)
+	t.Int("acme admins", len(admins.Admins), 1)
+	t.String("acme admin", admins.Admins[0].Name, "acme-admin")
+	t.Request(httptest.NewRequest("GET", "/admins/3", nil), 404, nil, acme)
+	t.Request(httptest.NewRequest("GET", "/admins", nil), 200, &admins, root)
+	t.Int("all admins", len(admins.Admins), 3)
+
+	req = httptest.NewRequest("GET", "/notes", nil)
+	req.Host = "api.example.com"
+	t.Request(req, 200, &notes, acme)
+	t.Int("acme notes", len(notes.Records), 1)
+	t.Int("acme note id", notes.Records[0].Id, 1)
+
+	backend.Default.SetCrossTenantAdmins(false)
+	req = httptest.NewRequest("GET", "/notes", nil)
+	req.Header.Set("X-Tenant", "globex")
+	t.Request(req, 404, nil, root)
+
+	memberships := backend.Default.ModelByName("TenantMembership")
+	var restMemberships []backend.TenantMembership
+	if err := backend.Default.DeletePermanently(tenants, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := memberships.Find().Query(&restMemberships); err != nil {
+		t.Fatal(err)
+	}
+	t.Int("memberships", len(restMemberships), 1)
+	t.Int("membership tenant id", restMemberships[0].TenantId, 2)
+	var restNotes []Note
+	if err := backend.Default.ModelByName("Note").Find().Query(&restNotes); err != nil {
+		t.Fatal(err)
+	}
+	t.Int("notes", len(restNotes), 1)
+	t.Int("note tenant id", restNotes[0].TenantId, 2)
+
+	if err := backend.Default.DeletePermanently(backend.Default.ModelByName("Admin"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := memberships.Find().Query(&restMemberships); err != nil {
+		t.Fatal(err)
+	}
+	t.Int("memberships", len(restMemberships), 0)
+}
+
+func TestIsUnique(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		backend.Default.ModelByName("Post").Insert("Title", "foo").MustExecute()
+		t.Bool("existing title", backend.Default.IsUnique(&Post{Title: "foo"}, "Title"), false)
+		t.Bool("existing title of itself", backend.Default.IsUnique(&Post{Id: 1, Title: "foo"}, "Title"), true)
+		t.Bool("new title", backend.Default.IsUnique(&Post{Title: "bar"}, "Title"), true)
+		t.Bool("unknown column", backend.Default.IsUnique(&Post{Title: "bar"}, "Unknown"), false)
+		t.Bool("unknown model", backend.Default.IsUnique(&struct{ Title string }{"bar"}, "Title"), false)
+	})
+}
@@ -0,0 +1,31 @@
+package backend
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdminUI(_t *testing.T) {
+	t := &test{_t}
+	testWithSqlite(func() {
+		testWithMountedApp(func() {
+			testAdminUI(t)
+		})
+	})
+}
+
+func testAdminUI(t *test) {
+	for _, path := range []string{"/ui", "/ui/", "/ui/#/Post", "/ui/Post/1"} {
+		body := t.RequestBody(httptest.NewRequest("GET", path, nil), 200)
+		t.Bool(path+" base", strings.Contains(body, `<base href="/ui/">`), true)
+		t.Bool(path+" api", strings.Contains(body, `<meta name="api" content="">`), true)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ui/app.js", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Int("status", resp.StatusCode, 200)
+	t.Bool("content type", strings.HasPrefix(resp.Header.Get("Content-Type"), "text/javascript"), true)
+}
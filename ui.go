@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"bytes"
+	"embed"
+	"html"
+	"mime"
+	"path"
+	"strings"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// FiberAdminUI returns handler serving the embedded admin web UI, a single
+// page application using the sessions and admins routes and the generic
+// model routes found in the OpenAPI document. The handler should be
+// registered at prefix+"/ui" and prefix+"/ui/*", where prefix is the prefix
+// of the routes registered by MountFiber(), which registers it by default.
+func (backend *Backend) FiberAdminUI(prefix string) FiberHandler {
+	index, _ := uiFiles.ReadFile("ui/index.html")
+	page := strings.NewReplacer(
+		"{{BASE}}", html.EscapeString(prefix+"/ui/"),
+		"{{API}}", html.EscapeString(prefix),
+	).Replace(string(index))
+	return func(c FiberCtx) error {
+		name := c.Params("*")
+		content, err := uiFiles.ReadFile("ui/" + name)
+		if name == "" || name == "index.html" || err != nil {
+			// unknown paths are routes of the application
+			c.Set("Content-Type", "text/html; charset=utf-8")
+			c.Set("Cache-Control", "no-cache")
+			return c.SendStream(strings.NewReader(page), len(page))
+		}
+		c.Set("Content-Type", mime.TypeByExtension(path.Ext(name)))
+		return c.SendStream(bytes.NewReader(content), len(content))
+	}
+}
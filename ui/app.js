@@ -0,0 +1,292 @@
+(function () {
+  'use strict';
+
+  var api = document.querySelector('meta[name=api]').getAttribute('content');
+  var main = document.getElementById('main');
+  var resources = null; // model name => { path }
+  var schemas = {};
+
+  function token() { return localStorage.getItem('token') || ''; }
+
+  function request(method, path, body, headers) {
+    var opts = { method: method, headers: Object.assign({ Authorization: token() }, headers) };
+    if (body !== undefined) {
+      opts.headers['Content-Type'] = 'application/json';
+      opts.body = JSON.stringify(body);
+    }
+    return fetch(path, opts).then(function (res) {
+      if (res.status === 401) {
+        localStorage.removeItem('token');
+        signIn();
+        throw new Error('unauthorized');
+      }
+      if (res.status === 204) return { status: 204, headers: res.headers, data: null };
+      return res.json().then(function (data) {
+        return { status: res.status, headers: res.headers, data: data };
+      });
+    });
+  }
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) {
+      if (k.slice(0, 2) === 'on') e.addEventListener(k.slice(2), attrs[k]);
+      else if (k === 'text') e.textContent = attrs[k];
+      else if (attrs[k] === true) e.setAttribute(k, '');
+      else if (attrs[k] !== false && attrs[k] != null) e.setAttribute(k, attrs[k]);
+    });
+    (children || []).forEach(function (c) { if (c) e.appendChild(c); });
+    return e;
+  }
+
+  function render() {
+    main.innerHTML = '';
+    for (var i = 0; i < arguments.length; i++) main.appendChild(arguments[i]);
+  }
+
+  // field returns value of Go field name of obj in any key case, like Token,
+  // token or created_at for CreatedAt.
+  function field(obj, name) {
+    if (!obj) return undefined;
+    var norm = function (k) { return k.replace(/_/g, '').toLowerCase(); };
+    var key = Object.keys(obj).filter(function (k) { return norm(k) === norm(name); })[0];
+    return key === undefined ? undefined : obj[key];
+  }
+
+  function errorText(e) {
+    return field(e, 'Type') + (field(e, 'Param') ? ' ' + field(e, 'Param') : '');
+  }
+
+  function errorsText(data) {
+    return (field(data, 'Errors') || []).map(function (e) { return field(e, 'Name') + ' ' + errorText(e); }).join(', ');
+  }
+
+  function signIn() {
+    document.getElementById('nav').innerHTML = '';
+    document.getElementById('me').innerHTML = '';
+    var err = el('div', { 'class': 'error' });
+    var form = el('form', { 'class': 'record signin', onsubmit: function (ev) {
+      ev.preventDefault();
+      request('POST', api + '/sign-in', { Name: form.Name.value, Password: form.Password.value }).then(function (res) {
+        if (res.status !== 200) {
+          err.textContent = errorsText(res.data);
+          return;
+        }
+        localStorage.setItem('token', field(res.data, 'Token'));
+        start();
+      });
+    } }, [
+      el('h1', { text: 'Sign in' }),
+      el('label', {}, [el('span', { text: 'Name' }), el('input', { name: 'Name', autofocus: true })]),
+      el('label', {}, [el('span', { text: 'Password' }), el('input', { name: 'Password', type: 'password' })]),
+      err,
+      el('button', { 'class': 'primary', type: 'submit', text: 'Sign in' })
+    ]);
+    render(form);
+  }
+
+  function signOut() {
+    request('POST', api + '/sign-out').catch(function () {}).then(function () {
+      localStorage.removeItem('token');
+      signIn();
+    });
+  }
+
+  // Resources are discovered from the List operations of the OpenAPI
+  // document.
+  function loadResources() {
+    if (resources) return Promise.resolve(resources);
+    return fetch(api + '/openapi.json').then(function (res) { return res.json(); }).then(function (doc) {
+      resources = {};
+      Object.keys(doc.paths).forEach(function (path) {
+        var op = doc.paths[path].get;
+        if (op && op.tags && op.operationId === op.tags[0] + '.List' && op.tags[0] !== 'Schema') {
+          resources[op.tags[0]] = { path: path };
+        }
+      });
+      return resources;
+    });
+  }
+
+  function loadSchema(model) {
+    if (schemas[model]) return Promise.resolve(schemas[model]);
+    return request('GET', api + '/schemas/' + encodeURIComponent(model)).then(function (res) {
+      schemas[model] = res.status === 200 ? res.data : null;
+      return schemas[model];
+    });
+  }
+
+  function start() {
+    request('GET', api + '/me').then(function (res) {
+      if (!res.data) return signIn();
+      document.getElementById('me').appendChild(el('span', {}, [
+        document.createTextNode(field(res.data, 'Name') + ' '),
+        el('a', { href: '#/', text: 'Sign out', onclick: function (ev) { ev.preventDefault(); signOut(); } })
+      ]));
+      return loadResources().then(function () {
+        var nav = document.getElementById('nav');
+        nav.innerHTML = '';
+        Object.keys(resources).forEach(function (model) {
+          nav.appendChild(el('a', { href: '#/' + model, 'data-model': model, text: model }));
+        });
+        route();
+      });
+    });
+  }
+
+  function route() {
+    if (!resources) return;
+    var parts = location.hash.replace(/^#\/?/, '').split('/').map(decodeURIComponent);
+    var model = parts[0] || Object.keys(resources)[0];
+    Array.prototype.forEach.call(document.querySelectorAll('#nav a'), function (a) {
+      a.className = a.getAttribute('data-model') === model ? 'active' : '';
+    });
+    if (!resources[model]) return render(el('p', { text: 'Nothing to manage.' }));
+    if (parts[1] === 'new') return edit(model, null);
+    if (parts[1]) return edit(model, parts[1]);
+    list(model, new URLSearchParams(location.hash.split('?')[1] || ''));
+  }
+
+  function list(model, params) {
+    var path = resources[model].path;
+    var q = new URLSearchParams(params);
+    q.set('pagination', 'cursor');
+    request('GET', path + '?' + q.toString()).then(function (res) {
+      if (res.status !== 200) return render(el('p', { 'class': 'error', text: JSON.stringify(res.data) }));
+      var key = Object.keys(res.data).filter(function (k) { return Array.isArray(res.data[k]); })[0];
+      var records = res.data[key] || [];
+      var columns = records.length ? Object.keys(records[0]).filter(function (k) {
+        return records[0][k] === null || typeof records[0][k] !== 'object';
+      }) : [];
+      var go = function (changes) {
+        var p = new URLSearchParams(params);
+        p.delete('after');
+        p.delete('before');
+        Object.keys(changes).forEach(function (k) { if (changes[k]) p.set(k, changes[k]); else p.delete(k); });
+        location.hash = '#/' + model + '?' + p.toString();
+      };
+      var search = el('input', { type: 'search', placeholder: 'Search', value: params.get('query') || '' });
+      var toolbar = el('form', { 'class': 'toolbar', onsubmit: function (ev) {
+        ev.preventDefault();
+        go({ query: search.value });
+      } }, [search, el('button', { type: 'button', 'class': 'primary', text: 'New', onclick: function () {
+        location.hash = '#/' + model + '/new';
+      } })]);
+      var head = el('tr', {}, columns.map(function (c) {
+        var sort = c.replace(/([a-z0-9])([A-Z])/g, '$1_$2').toLowerCase();
+        var order = params.get('sort') === sort && params.get('order') !== 'desc' ? 'desc' : 'asc';
+        return el('th', {}, [el('a', { href: '#', text: c, onclick: function (ev) {
+          ev.preventDefault();
+          go({ sort: sort, order: order });
+        } })]);
+      }));
+      var body = el('tbody', {}, records.map(function (r) {
+        return el('tr', { onclick: function () { location.hash = '#/' + model + '/' + field(r, 'Id'); } }, columns.map(function (c) {
+          return el('td', { text: r[c] === null ? '' : String(r[c]), title: r[c] === null ? '' : String(r[c]) });
+        }));
+      }));
+      var page = field(res.data, 'Pagination') || {};
+      var prev = field(page, 'Prev'), next = field(page, 'Next');
+      var pager = el('div', { 'class': 'pager' }, [
+        el('button', { text: 'Previous', disabled: !prev, onclick: function () { go({ before: prev }); } }),
+        el('button', { text: 'Next', disabled: !next, onclick: function () { go({ after: next }); } })
+      ]);
+      render(el('h1', { text: model }), toolbar, el('table', {}, [el('thead', {}, [head]), body]), pager);
+    });
+  }
+
+  function inputFor(name, prop, value) {
+    prop = prop || {};
+    var type = Array.isArray(prop.type) ? prop.type[0] : prop.type;
+    if (prop['enum']) {
+      return el('select', { name: name }, prop['enum'].map(function (v) {
+        return el('option', { value: v, text: v, selected: v === value });
+      }));
+    }
+    if (type === 'boolean') return el('input', { name: name, type: 'checkbox', checked: !!value });
+    var attrs = { name: name, value: value == null || prop.format === 'password' ? '' : value };
+    if (type === 'integer' || type === 'number') {
+      attrs.type = 'number';
+      attrs.step = type === 'integer' ? '1' : 'any';
+    } else if (prop.format === 'password') {
+      attrs.type = 'password';
+    } else if (prop.format === 'email') {
+      attrs.type = 'email';
+    }
+    if (prop.maxLength) attrs.maxlength = prop.maxLength;
+    return el('input', attrs);
+  }
+
+  function inputValue(input, prop) {
+    var type = Array.isArray(prop.type) ? prop.type[0] : prop.type;
+    if (input.type === 'checkbox') return input.checked;
+    if (input.value === '' && Array.isArray(prop.type) && prop.type.indexOf('null') > -1) return null;
+    if (type === 'integer' || type === 'number') return input.value === '' ? 0 : Number(input.value);
+    return input.value;
+  }
+
+  function edit(model, id) {
+    var path = resources[model].path;
+    Promise.all([
+      loadSchema(model),
+      id ? request('GET', path + '/' + encodeURIComponent(id)) : Promise.resolve({ status: 200, data: {} })
+    ]).then(function (results) {
+      var schema = results[0], res = results[1];
+      if (res.status !== 200) return render(el('p', { 'class': 'error', text: 'Not found.' }));
+      var record = res.data, etag = res.headers && res.headers.get('ETag');
+      var props = schema ? field(schema, 'Schema').properties : {};
+      var params = schema ? field(schema, 'Params')[id ? 'update' : 'create'] : Object.keys(record).filter(function (k) { return k.toLowerCase() !== 'id'; });
+      var errors = {};
+      var fields = params.map(function (name) {
+        errors[name] = el('div', { 'class': 'error' });
+        return el('label', {}, [el('span', { text: name }), inputFor(name, props[name], record[name]), errors[name]]);
+      });
+      var message = el('div', { 'class': 'error' });
+      var form = el('form', { 'class': 'record', onsubmit: function (ev) {
+        ev.preventDefault();
+        var body = {};
+        params.forEach(function (name) {
+          var prop = props[name] || {};
+          var value = inputValue(form.elements[name], prop);
+          if (prop.format === 'password' && value === '' && id) return;
+          if (!id || value !== record[name]) body[name] = value;
+        });
+        var headers = etag ? { 'If-Match': etag } : {};
+        request(id ? 'PATCH' : 'POST', id ? path + '/' + encodeURIComponent(id) : path, body, headers).then(function (res) {
+          Object.keys(errors).forEach(function (k) { errors[k].textContent = ''; });
+          message.textContent = '';
+          if (res.status === 200) {
+            location.hash = '#/' + model;
+          } else if (res.status === 400) {
+            (field(res.data, 'Errors') || []).forEach(function (e) {
+              var name = field(e, 'Name');
+              if (errors[name]) errors[name].textContent = errorText(e);
+              else message.textContent += name + ' ' + errorText(e) + ' ';
+            });
+          } else if (res.status === 412) {
+            message.textContent = 'Record has been changed by someone else, reload to see the changes.';
+          } else {
+            message.textContent = field(res.data, 'Message') || 'Error';
+          }
+        });
+      } }, fields.concat([
+        message,
+        el('div', { 'class': 'toolbar' }, [
+          el('button', { 'class': 'primary', type: 'submit', text: id ? 'Save' : 'Create' }),
+          el('button', { type: 'button', text: 'Cancel', onclick: function () { location.hash = '#/' + model; } }),
+          id ? el('button', { type: 'button', 'class': 'danger', text: 'Delete', onclick: function () {
+            if (!confirm('Delete ' + model + ' ' + id + '?')) return;
+            request('DELETE', path + '/' + encodeURIComponent(id), undefined, etag ? { 'If-Match': etag } : {}).then(function (res) {
+              if (res.status === 200 || res.status === 204) location.hash = '#/' + model;
+              else message.textContent = errorsText(res.data) || 'Error';
+            });
+          } }) : null
+        ])
+      ]));
+      render(el('h1', { text: id ? model + ' ' + id : 'New ' + model }), form);
+    });
+  }
+
+  window.addEventListener('hashchange', route);
+  if (token()) start(); else signIn();
+})();
@@ -0,0 +1,119 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gopsql/psql"
+)
+
+// AssignUpdate applies body to a copy of the current record of model m and
+// returns the new record and the changes of the permitted params whose values
+// have been changed, plus UpdatedAt. Changes is empty if nothing is changed.
+//
+// If replace is false, body is a JSON merge patch (RFC 7396): fields not in
+// body are kept, null resets field to its zero value, objects are merged into
+// the current values. If replace is true, body is the full replacement of the
+// permitted params: params not in body are reset to zero values.
+func (backend Backend) AssignUpdate(m *psql.Model, current interface{}, body []byte, params []string, replace bool) (record interface{}, changes []interface{}, err error) {
+	invalid := NewInputErrors("Body", "invalid")
+	var patch map[string]json.RawMessage
+	if len(bytes.TrimSpace(body)) > 0 {
+		if json.Unmarshal(body, &patch) != nil {
+			err = invalid
+			return
+		}
+	}
+
+	record = m.New().Interface()
+	rv := reflect.ValueOf(record).Elem()
+	cv := reflect.ValueOf(current).Elem()
+	rv.Set(cv)
+
+	if replace {
+		for _, param := range params {
+			if f := rv.FieldByName(param); f.IsValid() && f.CanSet() {
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+	}
+
+	for key, value := range patch {
+		param := findParam(params, key)
+		if param == "" {
+			delete(patch, key)
+			continue
+		}
+		f := rv.FieldByName(param)
+		if !f.IsValid() || !f.CanSet() {
+			continue
+		}
+		if string(bytes.TrimSpace(value)) == "null" {
+			f.Set(reflect.Zero(f.Type()))
+			delete(patch, key)
+			continue
+		}
+		if !replace && bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+			var target, p interface{}
+			b, _ := json.Marshal(cv.FieldByName(param).Interface())
+			if json.Unmarshal(b, &target) == nil && json.Unmarshal(value, &p) == nil {
+				patch[key], _ = json.Marshal(mergePatch(target, p))
+			}
+		}
+	}
+
+	if len(patch) > 0 {
+		b, _ := json.Marshal(patch)
+		if _, e := m.Assign(record, m.Permit(params...).Filter(b)); e != nil {
+			err = invalid
+			return
+		}
+	}
+
+	for _, param := range params {
+		f := rv.FieldByName(param)
+		if !f.IsValid() || !f.CanInterface() {
+			continue
+		}
+		if !reflect.DeepEqual(f.Interface(), cv.FieldByName(param).Interface()) {
+			changes = append(changes, param, f.Interface())
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	changes, err = m.Assign(record, append(changes, m.UpdatedAt())...)
+	return
+}
+
+// findParam returns the param matching key case-insensitively.
+func findParam(params []string, key string) string {
+	for _, param := range params {
+		if strings.EqualFold(param, key) {
+			return param
+		}
+	}
+	return ""
+}
+
+// mergePatch applies JSON merge patch to target, see RFC 7396.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}